@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultJWKSRefreshInterval    = time.Hour
+	DefaultJWKSMinRefreshInterval = 30 * time.Second
+)
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// JWKSKeyProvider caches the keys published at a JWKS URL. The set is
+// refetched once RefreshInterval has passed, or earlier when a token refers
+// to an unknown kid, which is how key rotation is picked up. Only one fetch
+// runs at a time and cached keys stay readable while it does. Fetches are at
+// least MinRefreshInterval apart, whether the last one succeeded or not.
+type JWKSKeyProvider struct {
+	URL                string
+	Client             *http.Client
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]interface{}
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	refreshing  chan struct{}
+	refreshErr  error
+}
+
+func NewJWKSKeyProvider(url string) *JWKSKeyProvider {
+	return &JWKSKeyProvider{
+		URL:                url,
+		Client:             &http.Client{Timeout: 10 * time.Second},
+		RefreshInterval:    DefaultJWKSRefreshInterval,
+		MinRefreshInterval: DefaultJWKSMinRefreshInterval,
+	}
+}
+
+func (provider *JWKSKeyProvider) GetKey(kid string) (interface{}, error) {
+	provider.mu.RLock()
+	key, found := provider.lookup(kid)
+	stale := time.Since(provider.fetchedAt) >= provider.RefreshInterval
+	throttled := time.Since(provider.attemptedAt) < provider.MinRefreshInterval
+	lastErr := provider.refreshErr
+	provider.mu.RUnlock()
+
+	if found {
+		// A stale key is still served; the refreshed set replaces it later.
+		if stale && !throttled {
+			go provider.refresh()
+		}
+		return key, nil
+	}
+
+	if throttled {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, ErrKeyNotFound
+	}
+
+	if err := provider.refresh(); err != nil {
+		return nil, err
+	}
+
+	provider.mu.RLock()
+	defer provider.mu.RUnlock()
+
+	if key, found = provider.lookup(kid); !found {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// lookup must be called with mu held.
+func (provider *JWKSKeyProvider) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(provider.keys) == 1 {
+		for _, key := range provider.keys {
+			return key, true
+		}
+	}
+
+	key, found := provider.keys[kid]
+	return key, found
+}
+
+// refresh fetches the key set without holding mu. Callers arriving while a
+// fetch is running wait for it and share its result instead of fetching
+// again.
+func (provider *JWKSKeyProvider) refresh() error {
+	provider.mu.Lock()
+	if done := provider.refreshing; done != nil {
+		provider.mu.Unlock()
+		<-done
+
+		provider.mu.RLock()
+		defer provider.mu.RUnlock()
+		return provider.refreshErr
+	}
+	done := make(chan struct{})
+	provider.refreshing = done
+	provider.mu.Unlock()
+
+	keys, err := provider.fetch()
+
+	provider.mu.Lock()
+	provider.attemptedAt = time.Now()
+	if err == nil {
+		provider.keys = keys
+		provider.fetchedAt = provider.attemptedAt
+	}
+	provider.refreshErr = err
+	provider.refreshing = nil
+	provider.mu.Unlock()
+	close(done)
+
+	return err
+}
+
+func (provider *JWKSKeyProvider) fetch() (map[string]interface{}, error) {
+	response, err := provider.Client.Get(provider.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS returned status %d", response.StatusCode)
+	}
+
+	var set jsonWebKeySet
+	if err := json.NewDecoder(response.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no usable signing keys")
+	}
+
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
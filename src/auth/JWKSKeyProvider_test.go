@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type JWKSKeyProviderUnitTestsSuite struct {
+	suite.Suite
+	key      *rsa.PrivateKey
+	requests int32
+	release  chan struct{}
+	server   *httptest.Server
+	provider *JWKSKeyProvider
+}
+
+func TestJWKSKeyProviderUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(JWKSKeyProviderUnitTestsSuite))
+}
+
+func (suite *JWKSKeyProviderUnitTestsSuite) SetupTest() {
+	suite.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	atomic.StoreInt32(&suite.requests, 0)
+	suite.release = make(chan struct{})
+
+	key := suite.key
+	release := suite.release
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&suite.requests, 1) > 1 {
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "key-1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	}))
+
+	suite.provider = NewJWKSKeyProvider(suite.server.URL)
+	suite.provider.MinRefreshInterval = 0
+}
+
+func (suite *JWKSKeyProviderUnitTestsSuite) TearDownTest() {
+	select {
+	case <-suite.release:
+	default:
+		close(suite.release)
+	}
+	suite.server.Close()
+}
+
+func (suite *JWKSKeyProviderUnitTestsSuite) TestGetKey_CachedKeyIsServedDuringRefresh() {
+	_, err := suite.provider.GetKey("key-1")
+	suite.Require().Nil(err)
+
+	go suite.provider.GetKey("key-2")
+	assert.Eventually(suite.T(), func() bool { return atomic.LoadInt32(&suite.requests) == 2 }, time.Second, 5*time.Millisecond)
+
+	start := time.Now()
+	key, err := suite.provider.GetKey("key-1")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), &suite.key.PublicKey, key)
+	assert.Less(suite.T(), time.Since(start), 100*time.Millisecond)
+}
+
+func (suite *JWKSKeyProviderUnitTestsSuite) TestGetKey_ConcurrentMissesShareOneFetch() {
+	_, err := suite.provider.GetKey("key-1")
+	suite.Require().Nil(err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = suite.provider.GetKey("key-2")
+		}(i)
+	}
+	assert.Eventually(suite.T(), func() bool { return atomic.LoadInt32(&suite.requests) == 2 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(suite.release)
+	wg.Wait()
+
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&suite.requests))
+	for _, err := range errs {
+		assert.Equal(suite.T(), ErrKeyNotFound, err)
+	}
+}
+
+func (suite *JWKSKeyProviderUnitTestsSuite) TestGetKey_StaleKeyIsServedWhileRefreshing() {
+	_, err := suite.provider.GetKey("key-1")
+	suite.Require().Nil(err)
+	suite.provider.RefreshInterval = 0
+
+	key, err := suite.provider.GetKey("key-1")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), &suite.key.PublicKey, key)
+	assert.Eventually(suite.T(), func() bool { return atomic.LoadInt32(&suite.requests) == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func (suite *JWKSKeyProviderUnitTestsSuite) TestGetKey_FailedFetchIsNotRetriedBeforeMinRefreshInterval() {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	provider := NewJWKSKeyProvider(server.URL)
+
+	_, firstErr := provider.GetKey("key-1")
+	_, secondErr := provider.GetKey("key-1")
+
+	assert.NotNil(suite.T(), firstErr)
+	assert.Equal(suite.T(), firstErr, secondErr)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,9 @@
+package auth
+
+import "errors"
+
+var ErrKeyNotFound = errors.New("signing key not found")
+
+type KeyProvider interface {
+	GetKey(kid string) (interface{}, error)
+}
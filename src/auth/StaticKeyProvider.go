@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type StaticKeyProvider struct {
+	Key interface{}
+}
+
+func NewStaticKeyProvider(path string) (*StaticKeyProvider, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+		return &StaticKeyProvider{Key: key}, nil
+	}
+
+	key, err := jwt.ParseECPublicKeyFromPEM(pem)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticKeyProvider{Key: key}, nil
+}
+
+func (provider *StaticKeyProvider) GetKey(kid string) (interface{}, error) {
+	return provider.Key, nil
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+var validMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+type Principal struct {
+	Subject string
+	Claims  jwt.MapClaims
+}
+
+type TokenVerifier struct {
+	Keys     KeyProvider
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+func NewTokenVerifier(keys KeyProvider, issuer string, audience string) *TokenVerifier {
+	return &TokenVerifier{
+		Keys:     keys,
+		Issuer:   issuer,
+		Audience: audience,
+		Leeway:   time.Minute,
+	}
+}
+
+func BearerToken(header string) (string, error) {
+	parts := strings.Fields(header)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", ErrMissingToken
+	}
+	return parts[1], nil
+}
+
+func (verifier *TokenVerifier) Verify(tokenString string) (*Principal, error) {
+	parser := jwt.Parser{ValidMethods: validMethods, SkipClaimsValidation: true}
+
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return verifier.Keys.GetKey(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	if err := verifier.validateClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err.Error())
+	}
+
+	return &Principal{Subject: claims["sub"].(string), Claims: claims}, nil
+}
+
+func (verifier *TokenVerifier) validateClaims(claims jwt.MapClaims) error {
+	now := time.Now()
+
+	if _, ok := claims["exp"]; !ok {
+		return errors.New("token has no expiry")
+	}
+	if !claims.VerifyExpiresAt(now.Add(-verifier.Leeway).Unix(), true) {
+		return errors.New("token is expired")
+	}
+	if !claims.VerifyNotBefore(now.Add(verifier.Leeway).Unix(), false) {
+		return errors.New("token is not valid yet")
+	}
+	if verifier.Issuer != "" && !claims.VerifyIssuer(verifier.Issuer, true) {
+		return errors.New("token has unexpected issuer")
+	}
+	if verifier.Audience != "" && !hasAudience(claims, verifier.Audience) {
+		return errors.New("token has unexpected audience")
+	}
+	if subject, ok := claims["sub"].(string); !ok || subject == "" {
+		return errors.New("token has no subject")
+	}
+
+	return nil
+}
+
+func hasAudience(claims jwt.MapClaims, audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, value := range aud {
+			if value == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testIssuer   = "https://issuer.test/"
+	testAudience = "notifications-ms"
+)
+
+type TokenVerifierUnitTestsSuite struct {
+	suite.Suite
+	key      *rsa.PrivateKey
+	kid      string
+	server   *httptest.Server
+	requests int32
+	verifier *TokenVerifier
+}
+
+func TestTokenVerifierUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(TokenVerifierUnitTestsSuite))
+}
+
+func (suite *TokenVerifierUnitTestsSuite) SetupTest() {
+	suite.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	suite.kid = "key-1"
+	atomic.StoreInt32(&suite.requests, 0)
+
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&suite.requests, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": suite.kid,
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(suite.key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(suite.key.PublicKey.E)).Bytes()),
+			}},
+		})
+	}))
+
+	provider := NewJWKSKeyProvider(suite.server.URL)
+	provider.MinRefreshInterval = 0
+	suite.verifier = NewTokenVerifier(provider, testIssuer, testAudience)
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *TokenVerifierUnitTestsSuite) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = suite.kid
+	signed, _ := token.SignedString(suite.key)
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "auth0|123",
+		"iss": testIssuer,
+		"aud": []string{testAudience, "https://issuer.test/userinfo"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestTokenVerifier_Verify_ValidToken() {
+	principal, err := suite.verifier.Verify(suite.sign(validClaims()))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "auth0|123", principal.Subject)
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestTokenVerifier_Verify_KeysAreCached() {
+	suite.verifier.Verify(suite.sign(validClaims()))
+	suite.verifier.Verify(suite.sign(validClaims()))
+
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestTokenVerifier_Verify_RotatedKeyIsFetched() {
+	suite.verifier.Verify(suite.sign(validClaims()))
+
+	suite.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	suite.kid = "key-2"
+	principal, err := suite.verifier.Verify(suite.sign(validClaims()))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "auth0|123", principal.Subject)
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestTokenVerifier_Verify_UnsignedTokenRejected() {
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims())
+	signed, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	_, err := suite.verifier.Verify(signed)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidToken)
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestTokenVerifier_Verify_ForeignKeyRejected() {
+	foreignKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims())
+	token.Header["kid"] = suite.kid
+	signed, _ := token.SignedString(foreignKey)
+
+	_, err := suite.verifier.Verify(signed)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidToken)
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestTokenVerifier_Verify_ExpiredTokenRejected() {
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	_, err := suite.verifier.Verify(suite.sign(claims))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidToken)
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestTokenVerifier_Verify_MissingExpiryRejected() {
+	claims := validClaims()
+	delete(claims, "exp")
+
+	_, err := suite.verifier.Verify(suite.sign(claims))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidToken)
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestTokenVerifier_Verify_WrongIssuerRejected() {
+	claims := validClaims()
+	claims["iss"] = "https://attacker.test/"
+
+	_, err := suite.verifier.Verify(suite.sign(claims))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidToken)
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestTokenVerifier_Verify_WrongAudienceRejected() {
+	claims := validClaims()
+	claims["aud"] = "another-api"
+
+	_, err := suite.verifier.Verify(suite.sign(claims))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidToken)
+}
+
+func (suite *TokenVerifierUnitTestsSuite) TestBearerToken_MalformedHeader() {
+	_, err := BearerToken("Bearer")
+
+	assert.Equal(suite.T(), ErrMissingToken, err)
+}
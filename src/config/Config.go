@@ -0,0 +1,475 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every setting of the service. Values are taken from the
+// defaults, then from the optional config file and finally from environment
+// variables, so the variables set by docker-compose always win.
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	AMQP     AMQPConfig     `yaml:"amqp"`
+	Events   EventsConfig   `yaml:"events"`
+	Outbox   OutboxConfig   `yaml:"outbox"`
+	Email    EmailConfig    `yaml:"email"`
+	Push     PushConfig     `yaml:"push"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port                   string `yaml:"port" env:"SERVER_PORT"`
+	ShutdownTimeoutSeconds int    `yaml:"shutdownTimeoutSeconds" env:"SHUTDOWN_TIMEOUT_SECONDS"`
+}
+
+type DatabaseConfig struct {
+	Host                   string `yaml:"host" env:"DATABASE_DOMAIN"`
+	Port                   string `yaml:"port" env:"DATABASE_PORT"`
+	Username               string `yaml:"username" env:"DATABASE_USERNAME"`
+	Password               string `yaml:"password" env:"DATABASE_PASSWORD"`
+	Name                   string `yaml:"name" env:"DATABASE_SCHEMA"`
+	SSLMode                string `yaml:"sslMode" env:"DATABASE_SSL_MODE"`
+	MaxOpenConns           int    `yaml:"maxOpenConns" env:"DATABASE_MAX_OPEN_CONNS"`
+	MaxIdleConns           int    `yaml:"maxIdleConns" env:"DATABASE_MAX_IDLE_CONNS"`
+	ConnMaxLifetimeSeconds int    `yaml:"connMaxLifetimeSeconds" env:"DATABASE_CONN_MAX_LIFETIME_SECONDS"`
+	MigrateOnStartup       bool   `yaml:"migrateOnStartup" env:"DATABASE_MIGRATE_ON_STARTUP"`
+}
+
+type AMQPConfig struct {
+	URL                  string `yaml:"url" env:"AMQP_SERVER_URL"`
+	Exchange             string `yaml:"exchange" env:"AMQP_EXCHANGE"`
+	Queue                string `yaml:"queue" env:"AMQP_QUEUE"`
+	RoutingKey           string `yaml:"routingKey" env:"AMQP_ROUTING_KEY"`
+	ConsumerTag          string `yaml:"consumerTag" env:"AMQP_CONSUMER_TAG"`
+	DeadLetterExchange   string `yaml:"deadLetterExchange" env:"AMQP_DEAD_LETTER_EXCHANGE"`
+	DeadLetterQueue      string `yaml:"deadLetterQueue" env:"AMQP_DEAD_LETTER_QUEUE"`
+	DeadLetterRoutingKey string `yaml:"deadLetterRoutingKey" env:"AMQP_DEAD_LETTER_ROUTING_KEY"`
+	RetryExchange        string `yaml:"retryExchange" env:"AMQP_RETRY_EXCHANGE"`
+	RetryMaxAttempts     int    `yaml:"retryMaxAttempts" env:"AMQP_RETRY_MAX_ATTEMPTS"`
+	RetryInitialDelayMs  int    `yaml:"retryInitialDelayMs" env:"AMQP_RETRY_INITIAL_DELAY_MS"`
+	RetryMaxDelayMs      int    `yaml:"retryMaxDelayMs" env:"AMQP_RETRY_MAX_DELAY_MS"`
+	Workers              int    `yaml:"workers" env:"AMQP_WORKERS"`
+	Prefetch             int    `yaml:"prefetch" env:"AMQP_PREFETCH"`
+	BatchSize            int    `yaml:"batchSize" env:"AMQP_BATCH_SIZE"`
+	BatchWindowMs        int    `yaml:"batchWindowMs" env:"AMQP_BATCH_WINDOW_MS"`
+}
+
+const (
+	EventsTransportHTTP = "http"
+	EventsTransportAMQP = "amqp"
+)
+
+type EventsConfig struct {
+	Transport               string `yaml:"transport" env:"EVENTS_TRANSPORT"`
+	URL                     string `yaml:"url" env:"EVENTS_MS"`
+	HealthCheck             bool   `yaml:"healthCheck" env:"HEALTH_CHECK_EVENTS_MS"`
+	RequestTimeoutMs        int    `yaml:"requestTimeoutMs" env:"EVENTS_MS_REQUEST_TIMEOUT_MS"`
+	DialTimeoutMs           int    `yaml:"dialTimeoutMs" env:"EVENTS_MS_DIAL_TIMEOUT_MS"`
+	MaxIdleConns            int    `yaml:"maxIdleConns" env:"EVENTS_MS_MAX_IDLE_CONNS"`
+	IdleConnTimeoutSeconds  int    `yaml:"idleConnTimeoutSeconds" env:"EVENTS_MS_IDLE_CONN_TIMEOUT_SECONDS"`
+	BreakerFailureThreshold int    `yaml:"breakerFailureThreshold" env:"EVENTS_MS_BREAKER_FAILURE_THRESHOLD"`
+	BreakerOpenSeconds      int    `yaml:"breakerOpenSeconds" env:"EVENTS_MS_BREAKER_OPEN_SECONDS"`
+	AMQPURL                 string `yaml:"amqpUrl" env:"EVENTS_AMQP_URL"`
+	AMQPExchange            string `yaml:"amqpExchange" env:"EVENTS_AMQP_EXCHANGE"`
+	AMQPQueue               string `yaml:"amqpQueue" env:"EVENTS_AMQP_QUEUE"`
+	AMQPRoutingKey          string `yaml:"amqpRoutingKey" env:"EVENTS_AMQP_ROUTING_KEY"`
+}
+
+type OutboxConfig struct {
+	PollIntervalMs    int `yaml:"pollIntervalMs" env:"OUTBOX_POLL_INTERVAL_MS"`
+	BatchSize         int `yaml:"batchSize" env:"OUTBOX_BATCH_SIZE"`
+	DeliveryTimeoutMs int `yaml:"deliveryTimeoutMs" env:"OUTBOX_DELIVERY_TIMEOUT_MS"`
+	MaxBackoffSeconds int `yaml:"maxBackoffSeconds" env:"OUTBOX_MAX_BACKOFF_SECONDS"`
+}
+
+type EmailConfig struct {
+	Enabled       bool   `yaml:"enabled" env:"EMAIL_ENABLED"`
+	From          string `yaml:"from" env:"EMAIL_FROM"`
+	SMTPHost      string `yaml:"smtpHost" env:"SMTP_HOST"`
+	SMTPPort      string `yaml:"smtpPort" env:"SMTP_PORT"`
+	SMTPUsername  string `yaml:"smtpUsername" env:"SMTP_USERNAME"`
+	SMTPPassword  string `yaml:"smtpPassword" env:"SMTP_PASSWORD"`
+	SMTPTimeoutMs int    `yaml:"smtpTimeoutMs" env:"SMTP_TIMEOUT_MS"`
+	QueueSize     int    `yaml:"queueSize" env:"EMAIL_QUEUE_SIZE"`
+	Workers       int    `yaml:"workers" env:"EMAIL_WORKERS"`
+}
+
+// PushProviderFake records pushes instead of sending them.
+const PushProviderFake = "fake"
+
+// PushConfig enables the push channel when Provider is set. Devices can be
+// registered either way.
+type PushConfig struct {
+	Provider      string `yaml:"provider" env:"PUSH_PROVIDER"`
+	SendTimeoutMs int    `yaml:"sendTimeoutMs" env:"PUSH_SEND_TIMEOUT_MS"`
+	QueueSize     int    `yaml:"queueSize" env:"PUSH_QUEUE_SIZE"`
+	Workers       int    `yaml:"workers" env:"PUSH_WORKERS"`
+}
+
+type AuthConfig struct {
+	JWKSURL       string `yaml:"jwksUrl" env:"AUTH_JWKS_URL"`
+	PublicKeyFile string `yaml:"publicKeyFile" env:"AUTH_PUBLIC_KEY_FILE"`
+	Issuer        string `yaml:"issuer" env:"AUTH_ISSUER"`
+	Audience      string `yaml:"audience" env:"AUTH_AUDIENCE"`
+}
+
+// TracingConfig turns tracing off when Enabled is false; the other settings
+// are then ignored.
+type TracingConfig struct {
+	Enabled       bool   `yaml:"enabled" env:"TRACING_ENABLED"`
+	ServiceName   string `yaml:"serviceName" env:"JAEGER_SERVICE_NAME"`
+	AgentHostPort string `yaml:"agentHostPort" env:"JAEGER_AGENT_HOST_PORT"`
+	LogSpans      bool   `yaml:"logSpans" env:"JAEGER_LOG_SPANS"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:                   "9095",
+			ShutdownTimeoutSeconds: 30,
+		},
+		Database: DatabaseConfig{
+			Port:                   "5432",
+			SSLMode:                "disable",
+			MaxOpenConns:           20,
+			MaxIdleConns:           5,
+			ConnMaxLifetimeSeconds: 300,
+		},
+		AMQP: AMQPConfig{
+			Exchange:             "AddNotification-MS-exchange",
+			Queue:                "AddNotification-MS",
+			RoutingKey:           "AddNotification-MS-routing-key",
+			ConsumerTag:          "AddNotification-MS-consumer",
+			DeadLetterExchange:   "AddNotification-MS-dead-letter-exchange",
+			DeadLetterQueue:      "AddNotification-MS-dead-letter",
+			DeadLetterRoutingKey: "AddNotification-MS-dead-letter-routing-key",
+			RetryExchange:        "AddNotification-MS-retry-exchange",
+			RetryMaxAttempts:     5,
+			RetryInitialDelayMs:  1000,
+			RetryMaxDelayMs:      60000,
+			Workers:              1,
+			Prefetch:             0,
+			BatchSize:            1,
+			BatchWindowMs:        100,
+		},
+		Events: EventsConfig{
+			Transport:               EventsTransportHTTP,
+			AMQPExchange:            "AddEvent-MS-exchange",
+			AMQPQueue:               "AddEvent-MS",
+			AMQPRoutingKey:          "AddEvent-MS-routing-key",
+			RequestTimeoutMs:        5000,
+			DialTimeoutMs:           1000,
+			MaxIdleConns:            10,
+			IdleConnTimeoutSeconds:  90,
+			BreakerFailureThreshold: 5,
+			BreakerOpenSeconds:      30,
+		},
+		Outbox: OutboxConfig{
+			PollIntervalMs:    1000,
+			BatchSize:         20,
+			DeliveryTimeoutMs: 5000,
+			MaxBackoffSeconds: 300,
+		},
+		Email: EmailConfig{
+			SMTPPort:      "587",
+			SMTPTimeoutMs: 10000,
+			QueueSize:     1000,
+			Workers:       2,
+		},
+		Push: PushConfig{
+			SendTimeoutMs: 5000,
+			QueueSize:     1000,
+			Workers:       2,
+		},
+		Tracing: TracingConfig{
+			Enabled:       true,
+			ServiceName:   "notifications-ms",
+			AgentHostPort: "jaeger:6831",
+			LogSpans:      true,
+		},
+	}
+}
+
+func (c DatabaseConfig) ConnectionString() string {
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		c.Host,
+		c.Username,
+		c.Password,
+		c.Name,
+		c.Port,
+		c.SSLMode,
+	)
+}
+
+func (c DatabaseConfig) ConnMaxLifetime() time.Duration {
+	return time.Duration(c.ConnMaxLifetimeSeconds) * time.Second
+}
+
+func (c ServerConfig) Addr() string {
+	return ":" + c.Port
+}
+
+func (c ServerConfig) ShutdownTimeout() time.Duration {
+	return time.Duration(c.ShutdownTimeoutSeconds) * time.Second
+}
+
+func (c AMQPConfig) BatchWindow() time.Duration {
+	return time.Duration(c.BatchWindowMs) * time.Millisecond
+}
+
+func (c AMQPConfig) RetryInitialDelay() time.Duration {
+	return time.Duration(c.RetryInitialDelayMs) * time.Millisecond
+}
+
+func (c AMQPConfig) RetryMaxDelay() time.Duration {
+	return time.Duration(c.RetryMaxDelayMs) * time.Millisecond
+}
+
+// EventsBrokerURL is the broker system events are published to, the consumer's
+// broker unless EVENTS_AMQP_URL says otherwise.
+func (c Config) EventsBrokerURL() string {
+	if c.Events.AMQPURL != "" {
+		return c.Events.AMQPURL
+	}
+	return c.AMQP.URL
+}
+
+func (c EventsConfig) RequestTimeout() time.Duration {
+	return time.Duration(c.RequestTimeoutMs) * time.Millisecond
+}
+
+func (c EventsConfig) DialTimeout() time.Duration {
+	return time.Duration(c.DialTimeoutMs) * time.Millisecond
+}
+
+func (c EventsConfig) IdleConnTimeout() time.Duration {
+	return time.Duration(c.IdleConnTimeoutSeconds) * time.Second
+}
+
+func (c EventsConfig) BreakerOpenTimeout() time.Duration {
+	return time.Duration(c.BreakerOpenSeconds) * time.Second
+}
+
+func (c OutboxConfig) PollInterval() time.Duration {
+	return time.Duration(c.PollIntervalMs) * time.Millisecond
+}
+
+func (c OutboxConfig) DeliveryTimeout() time.Duration {
+	return time.Duration(c.DeliveryTimeoutMs) * time.Millisecond
+}
+
+func (c OutboxConfig) MaxBackoff() time.Duration {
+	return time.Duration(c.MaxBackoffSeconds) * time.Second
+}
+
+func (c EmailConfig) SMTPTimeout() time.Duration {
+	return time.Duration(c.SMTPTimeoutMs) * time.Millisecond
+}
+
+func (c PushConfig) SendTimeout() time.Duration {
+	return time.Duration(c.SendTimeoutMs) * time.Millisecond
+}
+
+// ValidationError lists every problem found, so a misconfigured deployment
+// can be fixed in one go instead of one restart per missing variable.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+func (c Config) Validate() error {
+	v := &validator{}
+
+	v.port("SERVER_PORT", c.Server.Port)
+	v.positive("SHUTDOWN_TIMEOUT_SECONDS", c.Server.ShutdownTimeoutSeconds)
+
+	c.Database.validate(v)
+
+	v.url("AMQP_SERVER_URL", c.AMQP.URL, "amqp", "amqps")
+	v.required("AMQP_EXCHANGE", c.AMQP.Exchange)
+	v.required("AMQP_QUEUE", c.AMQP.Queue)
+	v.required("AMQP_ROUTING_KEY", c.AMQP.RoutingKey)
+	v.required("AMQP_CONSUMER_TAG", c.AMQP.ConsumerTag)
+	v.required("AMQP_DEAD_LETTER_EXCHANGE", c.AMQP.DeadLetterExchange)
+	v.required("AMQP_DEAD_LETTER_QUEUE", c.AMQP.DeadLetterQueue)
+	v.required("AMQP_DEAD_LETTER_ROUTING_KEY", c.AMQP.DeadLetterRoutingKey)
+	v.required("AMQP_RETRY_EXCHANGE", c.AMQP.RetryExchange)
+	v.positive("AMQP_RETRY_MAX_ATTEMPTS", c.AMQP.RetryMaxAttempts)
+	v.positive("AMQP_RETRY_INITIAL_DELAY_MS", c.AMQP.RetryInitialDelayMs)
+	v.positive("AMQP_RETRY_MAX_DELAY_MS", c.AMQP.RetryMaxDelayMs)
+	v.positive("AMQP_WORKERS", c.AMQP.Workers)
+	v.notNegative("AMQP_PREFETCH", c.AMQP.Prefetch)
+	v.positive("AMQP_BATCH_SIZE", c.AMQP.BatchSize)
+	v.positive("AMQP_BATCH_WINDOW_MS", c.AMQP.BatchWindowMs)
+
+	v.oneOf("EVENTS_TRANSPORT", c.Events.Transport, EventsTransportHTTP, EventsTransportAMQP)
+	if c.Events.Transport != EventsTransportAMQP || c.Events.HealthCheck {
+		v.url("EVENTS_MS", c.Events.URL, "http", "https")
+	}
+	if c.Events.Transport == EventsTransportAMQP {
+		if c.Events.AMQPURL != "" {
+			v.url("EVENTS_AMQP_URL", c.Events.AMQPURL, "amqp", "amqps")
+		}
+		v.required("EVENTS_AMQP_EXCHANGE", c.Events.AMQPExchange)
+		v.required("EVENTS_AMQP_QUEUE", c.Events.AMQPQueue)
+		v.required("EVENTS_AMQP_ROUTING_KEY", c.Events.AMQPRoutingKey)
+	}
+	v.positive("EVENTS_MS_REQUEST_TIMEOUT_MS", c.Events.RequestTimeoutMs)
+	v.positive("EVENTS_MS_DIAL_TIMEOUT_MS", c.Events.DialTimeoutMs)
+	v.positive("EVENTS_MS_MAX_IDLE_CONNS", c.Events.MaxIdleConns)
+	v.positive("EVENTS_MS_IDLE_CONN_TIMEOUT_SECONDS", c.Events.IdleConnTimeoutSeconds)
+	v.positive("EVENTS_MS_BREAKER_FAILURE_THRESHOLD", c.Events.BreakerFailureThreshold)
+	v.positive("EVENTS_MS_BREAKER_OPEN_SECONDS", c.Events.BreakerOpenSeconds)
+
+	v.positive("OUTBOX_POLL_INTERVAL_MS", c.Outbox.PollIntervalMs)
+	v.positive("OUTBOX_BATCH_SIZE", c.Outbox.BatchSize)
+	v.positive("OUTBOX_DELIVERY_TIMEOUT_MS", c.Outbox.DeliveryTimeoutMs)
+	v.positive("OUTBOX_MAX_BACKOFF_SECONDS", c.Outbox.MaxBackoffSeconds)
+
+	if c.Email.Enabled {
+		v.address("EMAIL_FROM", c.Email.From)
+		v.required("SMTP_HOST", c.Email.SMTPHost)
+		v.port("SMTP_PORT", c.Email.SMTPPort)
+		v.positive("SMTP_TIMEOUT_MS", c.Email.SMTPTimeoutMs)
+		v.positive("EMAIL_QUEUE_SIZE", c.Email.QueueSize)
+		v.positive("EMAIL_WORKERS", c.Email.Workers)
+	}
+
+	if c.Push.Provider != "" {
+		v.oneOf("PUSH_PROVIDER", c.Push.Provider, PushProviderFake)
+		v.positive("PUSH_SEND_TIMEOUT_MS", c.Push.SendTimeoutMs)
+		v.positive("PUSH_QUEUE_SIZE", c.Push.QueueSize)
+		v.positive("PUSH_WORKERS", c.Push.Workers)
+	}
+
+	v.required("AUTH_ISSUER", c.Auth.Issuer)
+	v.required("AUTH_AUDIENCE", c.Auth.Audience)
+	if c.Auth.JWKSURL == "" && c.Auth.PublicKeyFile == "" {
+		v.problem("either AUTH_JWKS_URL or AUTH_PUBLIC_KEY_FILE must be set")
+	}
+	if c.Auth.JWKSURL != "" {
+		v.url("AUTH_JWKS_URL", c.Auth.JWKSURL, "http", "https")
+	}
+
+	if c.Tracing.Enabled {
+		v.required("JAEGER_SERVICE_NAME", c.Tracing.ServiceName)
+		if _, _, err := net.SplitHostPort(c.Tracing.AgentHostPort); err != nil {
+			v.problem("JAEGER_AGENT_HOST_PORT must be in host:port form")
+		}
+	}
+
+	return v.err()
+}
+
+// Validate checks only the database settings, for commands such as migrate
+// that do not start the rest of the service.
+func (c DatabaseConfig) Validate() error {
+	v := &validator{}
+	c.validate(v)
+	return v.err()
+}
+
+func (c DatabaseConfig) validate(v *validator) {
+	v.required("DATABASE_DOMAIN", c.Host)
+	v.port("DATABASE_PORT", c.Port)
+	v.required("DATABASE_USERNAME", c.Username)
+	v.required("DATABASE_SCHEMA", c.Name)
+	v.required("DATABASE_SSL_MODE", c.SSLMode)
+	v.positive("DATABASE_MAX_OPEN_CONNS", c.MaxOpenConns)
+	v.notNegative("DATABASE_MAX_IDLE_CONNS", c.MaxIdleConns)
+	v.notNegative("DATABASE_CONN_MAX_LIFETIME_SECONDS", c.ConnMaxLifetimeSeconds)
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) err() error {
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (v *validator) problem(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(name, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.problem("%s is required", name)
+	}
+}
+
+func (v *validator) positive(name string, value int) {
+	if value <= 0 {
+		v.problem("%s must be greater than zero", name)
+	}
+}
+
+func (v *validator) notNegative(name string, value int) {
+	if value < 0 {
+		v.problem("%s must not be negative", name)
+	}
+}
+
+func (v *validator) oneOf(name, value string, options ...string) {
+	for _, option := range options {
+		if value == option {
+			return
+		}
+	}
+	v.problem("%s must be one of %s, got %q", name, strings.Join(options, ", "), value)
+}
+
+func (v *validator) address(name, value string) {
+	if value == "" {
+		v.problem("%s is required", name)
+		return
+	}
+	if _, err := mail.ParseAddress(value); err != nil {
+		v.problem("%s must be an email address, got %q", name, value)
+	}
+}
+
+func (v *validator) port(name, value string) {
+	if value == "" {
+		v.problem("%s is required", name)
+		return
+	}
+	if port, err := strconv.Atoi(value); err != nil || port <= 0 || port > 65535 {
+		v.problem("%s must be a port number, got %q", name, value)
+	}
+}
+
+func (v *validator) url(name, value string, schemes ...string) {
+	if value == "" {
+		v.problem("%s is required", name)
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		v.problem("%s must be an absolute URL", name)
+		return
+	}
+
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return
+		}
+	}
+	v.problem("%s must use one of the schemes %s", name, strings.Join(schemes, ", "))
+}
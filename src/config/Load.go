@@ -0,0 +1,132 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads the configuration and validates the result.
+func Load(path string) (*Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Read builds the configuration from the defaults, the optional file at path
+// and the environment without validating it. Files ending in .yaml or .yml
+// are read as YAML, anything else as KEY=VALUE lines like env.conf.
+func Read(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := loadFile(&cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(&cfg, lookupEnv); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// docker-compose passes unset variables through as empty strings, which
+// must not override values from the file.
+func lookupEnv(name string) (string, bool) {
+	value, ok := os.LookupEnv(name)
+	return value, ok && value != ""
+}
+
+func loadFile(cfg *Config, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		return nil
+	default:
+		values, err := godotenv.Unmarshal(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		return applyEnv(cfg, func(name string) (string, bool) {
+			value, ok := values[name]
+			return value, ok && value != ""
+		})
+	}
+}
+
+// applyEnv sets every field tagged with env whose variable lookup finds.
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	var problems []string
+	applyEnvToStruct(reflect.ValueOf(cfg).Elem(), lookup, &problems)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func applyEnvToStruct(value reflect.Value, lookup func(string) (string, bool), problems *[]string) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() == reflect.Struct {
+			applyEnvToStruct(field, lookup, problems)
+			continue
+		}
+
+		name := value.Type().Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(raw)
+		case reflect.Int:
+			parsed, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil {
+				*problems = append(*problems, fmt.Sprintf("%s must be an integer, got %q", name, raw))
+				continue
+			}
+			field.SetInt(int64(parsed))
+		case reflect.Bool:
+			parsed, err := strconv.ParseBool(strings.TrimSpace(raw))
+			if err != nil {
+				*problems = append(*problems, fmt.Sprintf("%s must be true or false, got %q", name, raw))
+				continue
+			}
+			field.SetBool(parsed)
+		}
+	}
+}
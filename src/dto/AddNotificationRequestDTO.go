@@ -0,0 +1,9 @@
+package dto
+
+// AddNotificationRequestDTO is a notification as producers send it. Email is
+// only used to deliver the notification; it is never stored and never leaves
+// the service with the notification.
+type AddNotificationRequestDTO struct {
+	NotificationDTO
+	Email string
+}
@@ -0,0 +1,13 @@
+package dto
+
+import (
+	"notifications-ms/src/model"
+	"time"
+)
+
+type DeviceDTO struct {
+	ID        int
+	Token     string
+	Platform  model.DevicePlatform
+	CreatedAt time.Time
+}
@@ -0,0 +1,5 @@
+package dto
+
+type MarkAsReadRequestDTO struct {
+	IDs []int
+}
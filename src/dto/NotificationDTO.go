@@ -1,9 +1,17 @@
 package dto
 
-import "notifications-ms/src/model"
+import (
+	"notifications-ms/src/model"
+	"time"
+)
 
 type NotificationDTO struct {
+	ID               int
 	Message          string
 	UserAuth0ID      string
 	NotificationType *model.NotificationType
+	Read             bool
+	ReadAt           *time.Time
+	CreatedAt        time.Time
+	IdempotencyKey   string
 }
@@ -0,0 +1,6 @@
+package dto
+
+type NotificationPageDTO struct {
+	Notifications []*NotificationDTO
+	NextCursor    string
+}
@@ -0,0 +1,8 @@
+package dto
+
+import "notifications-ms/src/model"
+
+type RegisterDeviceRequestDTO struct {
+	Token    string
+	Platform model.DevicePlatform
+}
@@ -0,0 +1,25 @@
+package dto
+
+const (
+	SocketCommandRead    = "read"
+	SocketCommandReadAll = "read-all"
+	SocketCommandDelete  = "delete"
+
+	SocketMessageNotification = "notification"
+	SocketMessageAck          = "ack"
+	SocketMessageError        = "error"
+)
+
+type SocketCommandDTO struct {
+	Type      string `json:"type"`
+	RequestID string `json:"requestId"`
+	IDs       []int  `json:"ids"`
+}
+
+type SocketMessageDTO struct {
+	Type         string           `json:"type"`
+	RequestID    string           `json:"requestId,omitempty"`
+	Notification *NotificationDTO `json:"notification,omitempty"`
+	Affected     *int64           `json:"affected,omitempty"`
+	Error        string           `json:"error,omitempty"`
+}
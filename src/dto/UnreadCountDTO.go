@@ -0,0 +1,13 @@
+package dto
+
+import "notifications-ms/src/model"
+
+type UnreadCountDTO struct {
+	Total  int64
+	ByType []*UnreadTypeCountDTO `json:",omitempty"`
+}
+
+type UnreadTypeCountDTO struct {
+	Type  model.NotificationType
+	Count int64
+}
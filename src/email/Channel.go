@@ -0,0 +1,103 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"notifications-ms/src/dto"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultQueueSize = 1000
+	DefaultWorkers   = 2
+)
+
+var (
+	emailsSent = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notification_emails_sent_total",
+		Help: "Number of notification emails sent.",
+	})
+	emailFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notification_email_failures_total",
+		Help: "Number of notification emails the SMTP server did not accept.",
+	})
+	emailsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "notification_emails_dropped_total",
+		Help: "Number of notification emails dropped because the send queue was full.",
+	})
+)
+
+// Channel emails new notifications to the address their producer gave.
+// Email only queues the email, so a slow SMTP server never holds up
+// ingestion; Run sends what is queued.
+type Channel struct {
+	Sender  Sender
+	From    string
+	Workers int
+	Logger  *logrus.Entry
+
+	queue chan *Message
+}
+
+func NewChannel(sender Sender, from string, queueSize int, logger *logrus.Entry) *Channel {
+	return &Channel{
+		Sender:  sender,
+		From:    from,
+		Workers: DefaultWorkers,
+		Logger:  logger,
+		queue:   make(chan *Message, queueSize),
+	}
+}
+
+func (channel *Channel) Email(notification *dto.NotificationDTO, to string) {
+	address, err := mail.ParseAddress(to)
+	if err != nil {
+		channel.Logger.Debug(fmt.Sprintf("Not emailing notification %d, invalid address: %v", notification.ID, err))
+		return
+	}
+
+	subject, body := Render(notification)
+	message := &Message{From: channel.From, To: address.Address, Subject: subject, Body: body}
+
+	select {
+	case channel.queue <- message:
+	default:
+		emailsDropped.Inc()
+		channel.Logger.Error(fmt.Sprintf("Email queue is full, dropping email for notification %d", notification.ID))
+	}
+}
+
+// Run sends queued emails until ctx is done. Emails still queued then are
+// dropped; the notifications themselves are already stored.
+func (channel *Channel) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < channel.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			channel.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (channel *Channel) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message := <-channel.queue:
+			if err := channel.Sender.Send(ctx, message); err != nil {
+				emailFailures.Inc()
+				channel.Logger.Error(fmt.Sprintf("Sending notification email failed: %v", err))
+				continue
+			}
+			emailsSent.Inc()
+		}
+	}
+}
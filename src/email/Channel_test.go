@@ -0,0 +1,90 @@
+package email
+
+import (
+	"context"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/model"
+	"notifications-ms/src/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ChannelUnitTestsSuite struct {
+	suite.Suite
+	server  *fakeSMTPServer
+	channel *Channel
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func TestChannelUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(ChannelUnitTestsSuite))
+}
+
+func (suite *ChannelUnitTestsSuite) SetupTest() {
+	suite.server = newFakeSMTPServer(suite.T())
+	sender := NewSMTPSender("127.0.0.1", suite.server.Port(), "", "")
+	suite.channel = NewChannel(sender, "notifications@example.com", 2, utils.Logger())
+}
+
+func (suite *ChannelUnitTestsSuite) TearDownTest() {
+	if suite.cancel != nil {
+		suite.cancel()
+		<-suite.done
+		suite.cancel = nil
+	}
+	suite.server.Close()
+}
+
+func (suite *ChannelUnitTestsSuite) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.cancel = cancel
+	suite.done = make(chan struct{})
+	go func() {
+		suite.channel.Run(ctx)
+		close(suite.done)
+	}()
+}
+
+func notification(ntype model.NotificationType) *dto.NotificationDTO {
+	return &dto.NotificationDTO{ID: 1, Message: "Jane followed you", UserAuth0ID: "auth0|1", NotificationType: &ntype}
+}
+
+func (suite *ChannelUnitTestsSuite) TestEmail_SendsEmail() {
+	suite.run()
+
+	suite.channel.Email(notification(model.Follow), "Jane Doe <jane@example.com>")
+
+	assert.Eventually(suite.T(), func() bool { return len(suite.server.Received()) == 1 }, time.Second, 10*time.Millisecond)
+	received := suite.server.Received()[0]
+	assert.Equal(suite.T(), "notifications@example.com", received.From)
+	assert.Equal(suite.T(), []string{"jane@example.com"}, received.To)
+	assert.Contains(suite.T(), received.Data, "Subject: You have a new follower")
+}
+
+func (suite *ChannelUnitTestsSuite) TestEmail_InvalidAddressIsSkipped() {
+	suite.channel.Email(notification(model.Follow), "")
+	suite.channel.Email(notification(model.Follow), "not an address")
+
+	assert.Empty(suite.T(), suite.channel.queue)
+}
+
+func (suite *ChannelUnitTestsSuite) TestEmail_DropsWhenQueueIsFull() {
+	for i := 0; i < 3; i++ {
+		suite.channel.Email(notification(model.Like), "jane@example.com")
+	}
+
+	assert.Equal(suite.T(), 2, len(suite.channel.queue))
+}
+
+func (suite *ChannelUnitTestsSuite) TestRender() {
+	subject, body := Render(notification(model.Comment))
+	assert.Equal(suite.T(), "Someone commented on your post", subject)
+	assert.Contains(suite.T(), body, "Jane followed you")
+
+	subject, _ = Render(&dto.NotificationDTO{Message: "Hello"})
+	assert.Equal(suite.T(), "You have a new notification", subject)
+}
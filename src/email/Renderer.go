@@ -0,0 +1,30 @@
+package email
+
+import (
+	"fmt"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/model"
+)
+
+var subjects = map[model.NotificationType]string{
+	model.Message: "You have a new message",
+	model.Follow:  "You have a new follower",
+	model.Like:    "Someone liked your post",
+	model.Comment: "Someone commented on your post",
+}
+
+const defaultSubject = "You have a new notification"
+
+// Render turns a notification into the subject and body of its email.
+func Render(notification *dto.NotificationDTO) (string, string) {
+	subject := defaultSubject
+	if notification.NotificationType != nil {
+		if typeSubject, ok := subjects[*notification.NotificationType]; ok {
+			subject = typeSubject
+		}
+	}
+
+	body := fmt.Sprintf("Hi,\n\n%s\n\nYou are receiving this email because this address was given for notifications to your account.\n", notification.Message)
+
+	return subject, body
+}
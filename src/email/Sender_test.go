@@ -0,0 +1,189 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type receivedMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTPServer speaks just enough SMTP to accept mail from net/smtp.
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	received []receivedMail
+	// rejectRcpt makes the server refuse every recipient.
+	rejectRcpt bool
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTPServer{listener: listener}
+	go server.serve()
+	return server
+}
+
+func (server *fakeSMTPServer) Close() {
+	server.listener.Close()
+}
+
+func (server *fakeSMTPServer) Port() string {
+	_, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	return port
+}
+
+func (server *fakeSMTPServer) Received() []receivedMail {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]receivedMail(nil), server.received...)
+}
+
+func (server *fakeSMTPServer) serve() {
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+		go server.handle(conn)
+	}
+}
+
+func (server *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost fake SMTP")
+	var current receivedMail
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			current = receivedMail{From: pathOf(line)}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			if server.rejectRcpt {
+				reply("550 No such user")
+				continue
+			}
+			current.To = append(current.To, pathOf(line))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			current.Data = data.String()
+			server.mu.Lock()
+			server.received = append(server.received, current)
+			server.mu.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// pathOf returns the address between the angle brackets of MAIL or RCPT.
+func pathOf(line string) string {
+	start, end := strings.Index(line, "<"), strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+type SMTPSenderUnitTestsSuite struct {
+	suite.Suite
+	server *fakeSMTPServer
+	sender *SMTPSender
+}
+
+func TestSMTPSenderUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(SMTPSenderUnitTestsSuite))
+}
+
+func (suite *SMTPSenderUnitTestsSuite) SetupTest() {
+	suite.server = newFakeSMTPServer(suite.T())
+	suite.sender = NewSMTPSender("127.0.0.1", suite.server.Port(), "", "")
+}
+
+func (suite *SMTPSenderUnitTestsSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *SMTPSenderUnitTestsSuite) TestSend_DeliversMessage() {
+	err := suite.sender.Send(context.Background(), &Message{
+		From:    "notifications@example.com",
+		To:      "user@example.com",
+		Subject: "You have a new follower",
+		Body:    "Hi,\n\nJane followed you – say hello!\n",
+	})
+
+	assert.Nil(suite.T(), err)
+	received := suite.server.Received()
+	assert.Equal(suite.T(), 1, len(received))
+	assert.Equal(suite.T(), "notifications@example.com", received[0].From)
+	assert.Equal(suite.T(), []string{"user@example.com"}, received[0].To)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(received[0].Data))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user@example.com", parsed.Header.Get("To"))
+	assert.Equal(suite.T(), "You have a new follower", parsed.Header.Get("Subject"))
+	body, _ := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	assert.Equal(suite.T(), "Hi,\r\n\r\nJane followed you – say hello!\r\n", string(body))
+}
+
+func (suite *SMTPSenderUnitTestsSuite) TestSend_RecipientRejected() {
+	suite.server.rejectRcpt = true
+
+	err := suite.sender.Send(context.Background(), &Message{From: "notifications@example.com", To: "nobody@example.com", Subject: "Hi", Body: "Hi"})
+
+	assert.ErrorContains(suite.T(), err, "No such user")
+	assert.Empty(suite.T(), suite.server.Received())
+}
+
+func (suite *SMTPSenderUnitTestsSuite) TestSend_ServerUnreachable() {
+	suite.server.Close()
+	suite.sender.Timeout = time.Second
+
+	err := suite.sender.Send(context.Background(), &Message{From: "notifications@example.com", To: "user@example.com", Subject: "Hi", Body: "Hi"})
+
+	assert.Error(suite.T(), err)
+}
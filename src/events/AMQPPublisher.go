@@ -0,0 +1,234 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"notifications-ms/src/dto"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	DefaultAMQPExchange   = "AddEvent-MS-exchange"
+	DefaultAMQPQueue      = "AddEvent-MS"
+	DefaultAMQPRoutingKey = "AddEvent-MS-routing-key"
+
+	amqpHandshakeTimeout = 30 * time.Second
+	amqpHeartbeat        = 10 * time.Second
+)
+
+var ErrEventNotConfirmed = errors.New("broker did not confirm the event")
+
+// amqpSession is the part of a confirm-mode channel the publisher uses.
+type amqpSession interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	NotifyReturn(c chan amqp.Return) chan amqp.Return
+	Close() error
+}
+
+// AMQPPublisher publishes system events to a durable queue that events-ms
+// consumes. Every event waits for a publisher confirm, so a nil error means
+// the broker has taken responsibility for it.
+type AMQPPublisher struct {
+	URL        string
+	Exchange   string
+	Queue      string
+	RoutingKey string
+
+	mu       sync.Mutex
+	session  amqpSession
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+	dial     func(ctx context.Context) (amqpSession, error)
+}
+
+func NewAMQPPublisher(url, exchange, queue, routingKey string) *AMQPPublisher {
+	publisher := &AMQPPublisher{URL: url, Exchange: exchange, Queue: queue, RoutingKey: routingKey}
+	publisher.dial = publisher.dialBroker
+	return publisher
+}
+
+// Publish sends one event at a time and waits for its confirm, which keeps
+// confirms trivially matched to the event they belong to.
+func (publisher *AMQPPublisher) Publish(ctx context.Context, event *dto.EventRequestDTO) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if err := publisher.connect(ctx); err != nil {
+		return err
+	}
+
+	err = publisher.session.Publish(publisher.Exchange, publisher.RoutingKey, true, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		Body:         body,
+	})
+	if err != nil {
+		publisher.reset()
+		return err
+	}
+
+	select {
+	case confirmation, ok := <-publisher.confirms:
+		if !ok {
+			publisher.reset()
+			return errors.New("broker channel closed before confirming the event")
+		}
+		if !confirmation.Ack {
+			return ErrEventNotConfirmed
+		}
+	case <-ctx.Done():
+		// A confirm arriving later would be taken for the next event's.
+		publisher.reset()
+		return ctx.Err()
+	}
+
+	// The broker sends basic.return before the ack of an unroutable message.
+	select {
+	case returned := <-publisher.returns:
+		return fmt.Errorf("broker returned the event: %s", returned.ReplyText)
+	default:
+		return nil
+	}
+}
+
+func (publisher *AMQPPublisher) Close() error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if publisher.session == nil {
+		return nil
+	}
+	err := publisher.session.Close()
+	publisher.session = nil
+	return err
+}
+
+func (publisher *AMQPPublisher) connect(ctx context.Context) error {
+	if publisher.session != nil {
+		return nil
+	}
+
+	session, err := publisher.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	publisher.session = session
+	publisher.confirms = session.NotifyPublish(make(chan amqp.Confirmation, 1))
+	publisher.returns = session.NotifyReturn(make(chan amqp.Return, 1))
+	return nil
+}
+
+func (publisher *AMQPPublisher) reset() {
+	if publisher.session != nil {
+		publisher.session.Close()
+		publisher.session = nil
+	}
+}
+
+// dialBroker connects within ctx, so a broker that does not answer cannot
+// hold the publisher, and the relay waiting on it, past the delivery timeout.
+func (publisher *AMQPPublisher) dialBroker(ctx context.Context) (amqpSession, error) {
+	connection, err := amqp.DialConfig(publisher.URL, amqp.Config{
+		Heartbeat: amqpHeartbeat,
+		Locale:    "en_US",
+		Dial: func(network, addr string) (net.Conn, error) {
+			deadline := time.Now().Add(amqpHandshakeTimeout)
+			if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+				deadline = ctxDeadline
+			}
+
+			dialer := &net.Dialer{Deadline: deadline}
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+
+			// The AMQP handshake runs under this deadline too; the client
+			// clears it once the connection is open.
+			if err := conn.SetDeadline(deadline); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return conn, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := connection.Channel()
+	if err == nil {
+		err = publisher.declareTopology(channel)
+	}
+	if err == nil {
+		err = channel.Confirm(false)
+	}
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+
+	return &brokerSession{Channel: channel, connection: connection}, nil
+}
+
+func (publisher *AMQPPublisher) declareTopology(channel *amqp.Channel) error {
+	err := channel.ExchangeDeclare(
+		publisher.Exchange,
+		"direct",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	queue, err := channel.QueueDeclare(
+		publisher.Queue,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return channel.QueueBind(
+		queue.Name,
+		publisher.RoutingKey,
+		publisher.Exchange,
+		false,
+		nil,
+	)
+}
+
+// brokerSession owns the connection behind its channel, so closing the
+// session releases both.
+type brokerSession struct {
+	*amqp.Channel
+	connection *amqp.Connection
+}
+
+func (session *brokerSession) Close() error {
+	return session.connection.Close()
+}
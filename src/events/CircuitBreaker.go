@@ -0,0 +1,143 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 30 * time.Second
+)
+
+var ErrCircuitOpen = errors.New("events-ms circuit breaker is open")
+
+type BreakerState int32
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (state BreakerState) String() string {
+	switch state {
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+var breakerStateGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "events_ms_circuit_breaker_state",
+	Help: "State of the events-ms circuit breaker (0 closed, 1 half-open, 2 open).",
+})
+
+var breakerRejectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "events_ms_circuit_breaker_rejected_total",
+	Help: "Number of events-ms requests rejected because the circuit breaker was open.",
+})
+
+var breakerTransitionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "events_ms_circuit_breaker_transitions_total",
+	Help: "Number of events-ms circuit breaker state changes, by the state entered.",
+}, []string{"state"})
+
+// CircuitBreaker opens after FailureThreshold consecutive failures and
+// rejects calls until OpenTimeout has passed. It then lets a single trial
+// call through: success closes it again, failure reopens it.
+type CircuitBreaker struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	breakerStateGauge.Set(float64(BreakerClosed))
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		OpenTimeout:      openTimeout,
+		now:              time.Now,
+	}
+}
+
+func (breaker *CircuitBreaker) State() BreakerState {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	return breaker.state
+}
+
+// Allow reports whether a call may go ahead. Every allowed call must be
+// followed by Success, Failure or Release.
+func (breaker *CircuitBreaker) Allow() error {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	if breaker.state == BreakerOpen && breaker.now().Sub(breaker.openedAt) >= breaker.OpenTimeout {
+		breaker.setState(BreakerHalfOpen)
+	}
+
+	switch breaker.state {
+	case BreakerOpen:
+		breakerRejectedTotal.Inc()
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if breaker.probing {
+			breakerRejectedTotal.Inc()
+			return ErrCircuitOpen
+		}
+		breaker.probing = true
+	}
+
+	return nil
+}
+
+func (breaker *CircuitBreaker) Success() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	breaker.failures = 0
+	breaker.probing = false
+	if breaker.state != BreakerClosed {
+		breaker.setState(BreakerClosed)
+	}
+}
+
+func (breaker *CircuitBreaker) Failure() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	breaker.failures++
+	breaker.probing = false
+	if breaker.state == BreakerHalfOpen || (breaker.state == BreakerClosed && breaker.failures >= breaker.FailureThreshold) {
+		breaker.openedAt = breaker.now()
+		breaker.setState(BreakerOpen)
+	}
+}
+
+// Release ends an allowed call that says nothing about the health of
+// events-ms, such as one cancelled by the caller.
+func (breaker *CircuitBreaker) Release() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.probing = false
+}
+
+func (breaker *CircuitBreaker) setState(state BreakerState) {
+	breaker.state = state
+	breakerStateGauge.Set(float64(state))
+	breakerTransitionsTotal.WithLabelValues(state.String()).Inc()
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CircuitBreakerUnitTestsSuite struct {
+	suite.Suite
+	now     time.Time
+	breaker *CircuitBreaker
+}
+
+func TestCircuitBreakerUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(CircuitBreakerUnitTestsSuite))
+}
+
+func (suite *CircuitBreakerUnitTestsSuite) SetupTest() {
+	suite.now = time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	suite.breaker = NewCircuitBreaker(3, 30*time.Second)
+	suite.breaker.now = func() time.Time { return suite.now }
+}
+
+func (suite *CircuitBreakerUnitTestsSuite) fail(times int) {
+	for i := 0; i < times; i++ {
+		assert.Nil(suite.T(), suite.breaker.Allow())
+		suite.breaker.Failure()
+	}
+}
+
+func (suite *CircuitBreakerUnitTestsSuite) TestOpensAfterConsecutiveFailures() {
+	suite.fail(2)
+	assert.Equal(suite.T(), BreakerClosed, suite.breaker.State())
+
+	suite.fail(1)
+
+	assert.Equal(suite.T(), BreakerOpen, suite.breaker.State())
+	assert.Equal(suite.T(), ErrCircuitOpen, suite.breaker.Allow())
+}
+
+func (suite *CircuitBreakerUnitTestsSuite) TestSuccessResetsFailureCount() {
+	suite.fail(2)
+	suite.breaker.Allow()
+	suite.breaker.Success()
+	suite.fail(2)
+
+	assert.Equal(suite.T(), BreakerClosed, suite.breaker.State())
+}
+
+func (suite *CircuitBreakerUnitTestsSuite) TestHalfOpenAllowsSingleTrial() {
+	suite.fail(3)
+	suite.now = suite.now.Add(30 * time.Second)
+
+	assert.Nil(suite.T(), suite.breaker.Allow())
+	assert.Equal(suite.T(), BreakerHalfOpen, suite.breaker.State())
+	assert.Equal(suite.T(), ErrCircuitOpen, suite.breaker.Allow())
+}
+
+func (suite *CircuitBreakerUnitTestsSuite) TestHalfOpenTrialSuccessCloses() {
+	suite.fail(3)
+	suite.now = suite.now.Add(30 * time.Second)
+
+	suite.breaker.Allow()
+	suite.breaker.Success()
+
+	assert.Equal(suite.T(), BreakerClosed, suite.breaker.State())
+	assert.Nil(suite.T(), suite.breaker.Allow())
+}
+
+func (suite *CircuitBreakerUnitTestsSuite) TestHalfOpenTrialFailureReopens() {
+	suite.fail(3)
+	suite.now = suite.now.Add(30 * time.Second)
+
+	suite.breaker.Allow()
+	suite.breaker.Failure()
+
+	assert.Equal(suite.T(), BreakerOpen, suite.breaker.State())
+	suite.now = suite.now.Add(29 * time.Second)
+	assert.Equal(suite.T(), ErrCircuitOpen, suite.breaker.Allow())
+}
+
+func (suite *CircuitBreakerUnitTestsSuite) TestReleaseFreesHalfOpenTrial() {
+	suite.fail(3)
+	suite.now = suite.now.Add(30 * time.Second)
+
+	suite.breaker.Allow()
+	suite.breaker.Release()
+
+	assert.Equal(suite.T(), BreakerHalfOpen, suite.breaker.State())
+	assert.Nil(suite.T(), suite.breaker.Allow())
+}
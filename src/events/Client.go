@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultRequestTimeout  = 5 * time.Second
+	DefaultDialTimeout     = time.Second
+	DefaultMaxIdleConns    = 10
+	DefaultIdleConnTimeout = 90 * time.Second
+)
+
+type ClientOptions struct {
+	RequestTimeout  time.Duration
+	DialTimeout     time.Duration
+	MaxIdleConns    int
+	IdleConnTimeout time.Duration
+}
+
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		RequestTimeout:  DefaultRequestTimeout,
+		DialTimeout:     DefaultDialTimeout,
+		MaxIdleConns:    DefaultMaxIdleConns,
+		IdleConnTimeout: DefaultIdleConnTimeout,
+	}
+}
+
+// NewClient returns the HTTP client used for every call to events-ms. It
+// keeps a pool of connections to the one host it talks to, bounds each
+// request with a timeout and fails fast with ErrCircuitOpen while breaker
+// is open. A nil breaker gives a client whose requests never touch a
+// breaker, as health probes need.
+func NewClient(options ClientOptions, breaker *CircuitBreaker) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   options.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          options.MaxIdleConns,
+		MaxIdleConnsPerHost:   options.MaxIdleConns,
+		IdleConnTimeout:       options.IdleConnTimeout,
+		TLSHandshakeTimeout:   options.DialTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	client := &http.Client{Transport: transport, Timeout: options.RequestTimeout}
+	if breaker != nil {
+		client.Transport = &breakerTransport{next: transport, breaker: breaker}
+	}
+	return client
+}
+
+type breakerTransport struct {
+	next    http.RoundTripper
+	breaker *CircuitBreaker
+}
+
+func (transport *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := transport.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	response, err := transport.next.RoundTrip(req)
+	switch {
+	case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+		transport.breaker.Release()
+	case err != nil || response.StatusCode >= 500:
+		transport.breaker.Failure()
+	default:
+		transport.breaker.Success()
+	}
+
+	return response, err
+}
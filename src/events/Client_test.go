@@ -0,0 +1,120 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientUnitTestsSuite struct {
+	suite.Suite
+	requests int32
+	status   int32
+	delay    time.Duration
+	server   *httptest.Server
+	breaker  *CircuitBreaker
+	client   *http.Client
+}
+
+func TestClientUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(ClientUnitTestsSuite))
+}
+
+func (suite *ClientUnitTestsSuite) SetupTest() {
+	suite.requests = 0
+	suite.status = http.StatusOK
+	suite.delay = 0
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&suite.requests, 1)
+		if suite.delay > 0 {
+			select {
+			case <-time.After(suite.delay):
+			case <-r.Context().Done():
+			}
+		}
+		w.WriteHeader(int(atomic.LoadInt32(&suite.status)))
+	}))
+
+	options := DefaultClientOptions()
+	options.RequestTimeout = 100 * time.Millisecond
+	suite.breaker = NewCircuitBreaker(2, time.Minute)
+	suite.client = NewClient(options, suite.breaker)
+}
+
+func (suite *ClientUnitTestsSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *ClientUnitTestsSuite) get() error {
+	response, err := suite.client.Get(suite.server.URL)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+func (suite *ClientUnitTestsSuite) TestServerErrorsOpenBreaker() {
+	suite.status = http.StatusBadGateway
+
+	assert.Nil(suite.T(), suite.get())
+	assert.Nil(suite.T(), suite.get())
+	err := suite.get()
+
+	assert.True(suite.T(), errors.Is(err, ErrCircuitOpen))
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&suite.requests))
+	assert.Equal(suite.T(), BreakerOpen, suite.breaker.State())
+}
+
+func (suite *ClientUnitTestsSuite) TestClientErrorsDoNotOpenBreaker() {
+	suite.status = http.StatusBadRequest
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(suite.T(), suite.get())
+	}
+
+	assert.Equal(suite.T(), BreakerClosed, suite.breaker.State())
+}
+
+func (suite *ClientUnitTestsSuite) TestSlowServerTimesOut() {
+	suite.delay = time.Second
+
+	start := time.Now()
+	err := suite.get()
+
+	assert.Error(suite.T(), err)
+	assert.Less(suite.T(), time.Since(start), time.Second)
+}
+
+func (suite *ClientUnitTestsSuite) TestClientWithoutBreakerLeavesBreakerAlone() {
+	suite.status = http.StatusBadGateway
+	suite.client = NewClient(DefaultClientOptions(), nil)
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(suite.T(), suite.get())
+	}
+
+	assert.Equal(suite.T(), int32(3), atomic.LoadInt32(&suite.requests))
+	assert.Equal(suite.T(), BreakerClosed, suite.breaker.State())
+}
+
+func (suite *ClientUnitTestsSuite) TestCancelledRequestsDoNotOpenBreaker() {
+	suite.delay = time.Second
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, suite.server.URL, nil)
+		time.AfterFunc(10*time.Millisecond, cancel)
+		_, err := suite.client.Do(req)
+		assert.Error(suite.T(), err)
+	}
+
+	assert.Equal(suite.T(), BreakerClosed, suite.breaker.State())
+}
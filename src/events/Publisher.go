@@ -0,0 +1,52 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"notifications-ms/src/dto"
+)
+
+// TimestampLayout is the timestamp format events-ms expects.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+type Publisher interface {
+	Publish(ctx context.Context, event *dto.EventRequestDTO) error
+}
+
+// HTTPPublisher posts system events to the events-ms REST endpoint.
+type HTTPPublisher struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPPublisher(url string, client *http.Client) *HTTPPublisher {
+	return &HTTPPublisher{URL: url, Client: client}
+}
+
+func (publisher *HTTPPublisher) Publish(ctx context.Context, event *dto.EventRequestDTO) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, publisher.URL, bytes.NewBuffer(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+
+	response, err := publisher.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("events-ms responded with status %d", response.StatusCode)
+	}
+
+	return nil
+}
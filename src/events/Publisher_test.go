@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notifications-ms/src/dto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HTTPPublisherUnitTestsSuite struct {
+	suite.Suite
+}
+
+func TestHTTPPublisherUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(HTTPPublisherUnitTestsSuite))
+}
+
+func (suite *HTTPPublisherUnitTestsSuite) TestPublish_PostsEvent() {
+	var received dto.EventRequestDTO
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(suite.T(), http.MethodPost, r.Method)
+		assert.Equal(suite.T(), "application/json", r.Header.Get("content-type"))
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	publisher := NewHTTPPublisher(server.URL, server.Client())
+	err := publisher.Publish(context.Background(), &dto.EventRequestDTO{Timestamp: "2022-06-01 12:30:00", Message: "Hello"})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), dto.EventRequestDTO{Timestamp: "2022-06-01 12:30:00", Message: "Hello"}, received)
+}
+
+func (suite *HTTPPublisherUnitTestsSuite) TestPublish_ErrorStatus() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	publisher := NewHTTPPublisher(server.URL, server.Client())
+	err := publisher.Publish(context.Background(), &dto.EventRequestDTO{Message: "Hello"})
+
+	assert.EqualError(suite.T(), err, "events-ms responded with status 503")
+}
+
+func (suite *HTTPPublisherUnitTestsSuite) TestPublish_Unreachable() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	publisher := NewHTTPPublisher(server.URL, server.Client())
+	err := publisher.Publish(context.Background(), &dto.EventRequestDTO{Message: "Hello"})
+
+	assert.Error(suite.T(), err)
+}
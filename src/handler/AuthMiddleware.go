@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"notifications-ms/src/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const principalKey = "principal"
+
+func AuthMiddleware(verifier *auth.TokenVerifier, logger *logrus.Entry) gin.HandlerFunc {
+	return authenticate(verifier, logger, false)
+}
+
+// QueryTokenAuthMiddleware additionally accepts the token in the access_token
+// query parameter, for browser EventSource and WebSocket clients that cannot
+// set an Authorization header.
+func QueryTokenAuthMiddleware(verifier *auth.TokenVerifier, logger *logrus.Entry) gin.HandlerFunc {
+	return authenticate(verifier, logger, true)
+}
+
+func authenticate(verifier *auth.TokenVerifier, logger *logrus.Entry, allowQueryToken bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token, err := auth.BearerToken(ctx.Request.Header.Get("Authorization"))
+		if err != nil && allowQueryToken && ctx.Query("access_token") != "" {
+			token, err = ctx.Query("access_token"), nil
+		}
+		if err != nil {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			abortUnauthorized(ctx, err.Error())
+			return
+		}
+
+		principal, err := verifier.Verify(token)
+		if err != nil {
+			logger.Debug(err.Error())
+			ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			abortUnauthorized(ctx, auth.ErrInvalidToken.Error())
+			return
+		}
+
+		ctx.Set(principalKey, principal)
+		ctx.Next()
+	}
+}
+
+func abortUnauthorized(ctx *gin.Context, message string) {
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+func getPrincipal(ctx *gin.Context) *auth.Principal {
+	return ctx.MustGet(principalKey).(*auth.Principal)
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notifications-ms/src/auth"
+	"notifications-ms/src/utils"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthMiddlewareUnitTestsSuite struct {
+	suite.Suite
+	key    *rsa.PrivateKey
+	router *gin.Engine
+}
+
+func TestAuthMiddlewareUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(AuthMiddlewareUnitTestsSuite))
+}
+
+func (suite *AuthMiddlewareUnitTestsSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+	suite.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+	verifier := auth.NewTokenVerifier(&auth.StaticKeyProvider{Key: &suite.key.PublicKey}, "issuer", "audience")
+
+	suite.router = gin.New()
+	suite.router.GET("/protected", AuthMiddleware(verifier, utils.Logger()), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"subject": getPrincipal(ctx).Subject})
+	})
+}
+
+func (suite *AuthMiddlewareUnitTestsSuite) request(authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func (suite *AuthMiddlewareUnitTestsSuite) token(key *rsa.PrivateKey) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": "auth0|123",
+		"iss": "issuer",
+		"aud": "audience",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	signed, _ := token.SignedString(key)
+	return signed
+}
+
+func (suite *AuthMiddlewareUnitTestsSuite) TestAuthMiddleware_MissingHeader() {
+	recorder := suite.request("")
+
+	var body map[string]string
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, recorder.Code)
+	assert.Equal(suite.T(), auth.ErrMissingToken.Error(), body["error"])
+}
+
+func (suite *AuthMiddlewareUnitTestsSuite) TestAuthMiddleware_MalformedHeader() {
+	recorder := suite.request("Bearer")
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, recorder.Code)
+}
+
+func (suite *AuthMiddlewareUnitTestsSuite) TestAuthMiddleware_InvalidSignature() {
+	foreignKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	recorder := suite.request("Bearer " + suite.token(foreignKey))
+
+	var body map[string]string
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, recorder.Code)
+	assert.Equal(suite.T(), auth.ErrInvalidToken.Error(), body["error"])
+}
+
+func (suite *AuthMiddlewareUnitTestsSuite) TestAuthMiddleware_ValidToken() {
+	recorder := suite.request("Bearer " + suite.token(suite.key))
+
+	var body map[string]string
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+	assert.Equal(suite.T(), "auth0|123", body["subject"])
+}
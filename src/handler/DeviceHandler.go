@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+)
+
+type DeviceHandler struct {
+	Service *service.DeviceService
+	Logger  *logrus.Entry
+}
+
+func (handler *DeviceHandler) RegisterDevice(ctx *gin.Context) {
+	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "POST /devices")
+	defer span.Finish()
+
+	var request dto.RegisterDeviceRequestDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal := getPrincipal(ctx)
+
+	handler.Logger.Info(fmt.Sprintf("Registering device for user %s", principal.Subject))
+	device, err := handler.Service.RegisterDevice(principal.Subject, &request)
+	if errors.Is(err, service.ErrInvalidDevice) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, device)
+}
+
+func (handler *DeviceHandler) GetDevices(ctx *gin.Context) {
+	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "GET /devices")
+	defer span.Finish()
+
+	principal := getPrincipal(ctx)
+
+	handler.Logger.Info(fmt.Sprintf("Getting devices for user %s", principal.Subject))
+	devices, err := handler.Service.GetDevices(principal.Subject)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, devices)
+}
+
+func (handler *DeviceHandler) UnregisterDevice(ctx *gin.Context) {
+	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "DELETE /devices/:id")
+	defer span.Finish()
+
+	id, err := getId(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal := getPrincipal(ctx)
+
+	handler.Logger.Info(fmt.Sprintf("Unregistering device %d for user %s", id, principal.Subject))
+	err = handler.Service.UnregisterDevice(id, principal.Subject)
+	if errors.Is(err, service.ErrDeviceNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
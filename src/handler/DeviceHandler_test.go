@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"notifications-ms/src/auth"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"notifications-ms/src/service"
+	"notifications-ms/src/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type DeviceHandlerUnitTestsSuite struct {
+	suite.Suite
+	repositoryMock *repository.DeviceRepositoryMock
+	router         *gin.Engine
+}
+
+func TestDeviceHandlerUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(DeviceHandlerUnitTestsSuite))
+}
+
+func (suite *DeviceHandlerUnitTestsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.repositoryMock = new(repository.DeviceRepositoryMock)
+
+	deviceHandler := DeviceHandler{
+		Service: &service.DeviceService{DeviceRepo: suite.repositoryMock, Logger: utils.Logger()},
+		Logger:  utils.Logger(),
+	}
+
+	suite.router = gin.New()
+	devices := suite.router.Group("/devices", func(ctx *gin.Context) {
+		ctx.Set(principalKey, &auth.Principal{Subject: "auth0|1"})
+	})
+	devices.GET("", deviceHandler.GetDevices)
+	devices.POST("", deviceHandler.RegisterDevice)
+	devices.DELETE("/:id", deviceHandler.UnregisterDevice)
+}
+
+func (suite *DeviceHandlerUnitTestsSuite) request(method, target, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return recorder
+}
+
+func (suite *DeviceHandlerUnitTestsSuite) TestRegisterDevice_Created() {
+	createdAt := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	suite.repositoryMock.On("RegisterDevice", &model.Device{UserAuth0ID: "auth0|1", Token: "fcm-token", Platform: model.FCM}).Run(func(args mock.Arguments) {
+		device := args.Get(0).(*model.Device)
+		device.ID = 3
+		device.CreatedAt = createdAt
+	}).Return("", nil).Once()
+
+	recorder := suite.request(http.MethodPost, "/devices", `{"Token":" fcm-token ","Platform":"FCM"}`)
+
+	assert.Equal(suite.T(), http.StatusCreated, recorder.Code)
+	assert.JSONEq(suite.T(), `{"ID":3,"Token":"fcm-token","Platform":"fcm","CreatedAt":"2022-06-01T12:00:00Z"}`, recorder.Body.String())
+}
+
+func (suite *DeviceHandlerUnitTestsSuite) TestRegisterDevice_Invalid() {
+	for _, body := range []string{`not json`, `{"Token":"","Platform":"fcm"}`, `{"Token":"abc","Platform":"sms"}`} {
+		recorder := suite.request(http.MethodPost, "/devices", body)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, recorder.Code, body)
+	}
+	suite.repositoryMock.AssertNotCalled(suite.T(), "RegisterDevice", mock.Anything)
+}
+
+func (suite *DeviceHandlerUnitTestsSuite) TestGetDevices() {
+	suite.repositoryMock.On("GetDevicesByUserAuth0ID", "auth0|1").Return([]*model.Device{
+		{ID: 1, UserAuth0ID: "auth0|1", Token: "apns-token", Platform: model.APNs},
+	}, nil).Once()
+
+	recorder := suite.request(http.MethodGet, "/devices", "")
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+	assert.JSONEq(suite.T(), `[{"ID":1,"Token":"apns-token","Platform":"apns","CreatedAt":"0001-01-01T00:00:00Z"}]`, recorder.Body.String())
+}
+
+func (suite *DeviceHandlerUnitTestsSuite) TestUnregisterDevice() {
+	suite.repositoryMock.On("UnregisterDevice", 3, "auth0|1").Return(nil).Once()
+
+	recorder := suite.request(http.MethodDelete, "/devices/3", "")
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+}
+
+func (suite *DeviceHandlerUnitTestsSuite) TestUnregisterDevice_InvalidID() {
+	recorder := suite.request(http.MethodDelete, "/devices/fcm-token", "")
+
+	assert.Equal(suite.T(), http.StatusBadRequest, recorder.Code)
+	suite.repositoryMock.AssertNotCalled(suite.T(), "UnregisterDevice", mock.Anything, mock.Anything)
+}
+
+func (suite *DeviceHandlerUnitTestsSuite) TestUnregisterDevice_ForeignOrMissingDevice() {
+	suite.repositoryMock.On("UnregisterDevice", 4, "auth0|1").Return(gorm.ErrRecordNotFound).Once()
+
+	recorder := suite.request(http.MethodDelete, "/devices/4", "")
+
+	assert.Equal(suite.T(), http.StatusNotFound, recorder.Code)
+	assert.JSONEq(suite.T(), `{"error":"device not found"}`, recorder.Body.String())
+}
+
+func (suite *DeviceHandlerUnitTestsSuite) TestUnregisterDevice_StorageError() {
+	suite.repositoryMock.On("UnregisterDevice", 3, "auth0|1").Return(errors.New("database is down")).Once()
+
+	recorder := suite.request(http.MethodDelete, "/devices/3", "")
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, recorder.Code)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthCheck is one dependency reported by /readyz. Only failing critical
+// checks make the service unready; the others are reported for information.
+type HealthCheck struct {
+	Name     string
+	Check    HealthCheckFunc
+	Critical bool
+}
+
+type HealthHandler struct {
+	Checks  []HealthCheck
+	Timeout time.Duration
+}
+
+type dependencyStatus struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+func (handler *HealthHandler) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (handler *HealthHandler) Readiness(ctx *gin.Context) {
+	timeout := handler.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+	defer cancel()
+
+	statuses := make([]dependencyStatus, len(handler.Checks))
+	var wg sync.WaitGroup
+	for i, check := range handler.Checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			statuses[i] = runHealthCheck(checkCtx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	status, code := "ok", http.StatusOK
+	dependencies := make(map[string]dependencyStatus, len(statuses))
+	for i, dependency := range statuses {
+		dependencies[handler.Checks[i].Name] = dependency
+		if dependency.Status != "up" && dependency.Critical {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+
+	ctx.JSON(code, gin.H{"status": status, "dependencies": dependencies})
+}
+
+func runHealthCheck(ctx context.Context, check HealthCheck) dependencyStatus {
+	start := time.Now()
+	err := check.Check(ctx)
+
+	status := dependencyStatus{Status: "up", Critical: check.Critical, Duration: time.Since(start).String()}
+	if err != nil {
+		status.Status = "down"
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// HTTPReachabilityCheck treats any response below 500 as reachable, since the
+// checked endpoint is not necessarily meant to be called with GET.
+func HTTPReachabilityCheck(client *http.Client, url string) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		response, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+
+		if response.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("responded with status %d", response.StatusCode)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HealthHandlerUnitTestsSuite struct {
+	suite.Suite
+}
+
+func TestHealthHandlerUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(HealthHandlerUnitTestsSuite))
+}
+
+type readinessResponse struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+func (suite *HealthHandlerUnitTestsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *HealthHandlerUnitTestsSuite) serve(healthHandler *HealthHandler, path string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func (suite *HealthHandlerUnitTestsSuite) readiness(healthHandler *HealthHandler) (int, readinessResponse) {
+	recorder := suite.serve(healthHandler, "/readyz")
+
+	var response readinessResponse
+	assert.NoError(suite.T(), json.Unmarshal(recorder.Body.Bytes(), &response))
+	return recorder.Code, response
+}
+
+func up(ctx context.Context) error { return nil }
+
+func (suite *HealthHandlerUnitTestsSuite) TestHealthHandler_Liveness_IgnoresDependencies() {
+	healthHandler := &HealthHandler{Checks: []HealthCheck{
+		{Name: "database", Check: func(ctx context.Context) error { return errors.New("down") }, Critical: true},
+	}}
+
+	recorder := suite.serve(healthHandler, "/healthz")
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+}
+
+func (suite *HealthHandlerUnitTestsSuite) TestHealthHandler_Readiness_AllUp() {
+	healthHandler := &HealthHandler{Checks: []HealthCheck{
+		{Name: "database", Check: up, Critical: true},
+		{Name: "rabbitmq", Check: up, Critical: true},
+	}}
+
+	code, response := suite.readiness(healthHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), "ok", response.Status)
+	assert.Equal(suite.T(), "up", response.Dependencies["database"].Status)
+	assert.Equal(suite.T(), "up", response.Dependencies["rabbitmq"].Status)
+}
+
+func (suite *HealthHandlerUnitTestsSuite) TestHealthHandler_Readiness_CriticalDown() {
+	healthHandler := &HealthHandler{Checks: []HealthCheck{
+		{Name: "database", Check: up, Critical: true},
+		{Name: "rabbitmq", Check: func(ctx context.Context) error { return errors.New("consumer is disconnected") }, Critical: true},
+	}}
+
+	code, response := suite.readiness(healthHandler)
+
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, code)
+	assert.Equal(suite.T(), "unavailable", response.Status)
+	assert.Equal(suite.T(), "down", response.Dependencies["rabbitmq"].Status)
+	assert.Equal(suite.T(), "consumer is disconnected", response.Dependencies["rabbitmq"].Error)
+}
+
+func (suite *HealthHandlerUnitTestsSuite) TestHealthHandler_Readiness_OptionalDownStaysReady() {
+	healthHandler := &HealthHandler{Checks: []HealthCheck{
+		{Name: "database", Check: up, Critical: true},
+		{Name: "events-ms", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+	}}
+
+	code, response := suite.readiness(healthHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Equal(suite.T(), "down", response.Dependencies["events-ms"].Status)
+}
+
+func (suite *HealthHandlerUnitTestsSuite) TestHealthHandler_Readiness_CheckTimesOut() {
+	healthHandler := &HealthHandler{
+		Timeout: 20 * time.Millisecond,
+		Checks: []HealthCheck{
+			{Name: "database", Check: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}, Critical: true},
+		},
+	}
+
+	code, response := suite.readiness(healthHandler)
+
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, code)
+	assert.Equal(suite.T(), context.DeadlineExceeded.Error(), response.Dependencies["database"].Error)
+}
+
+func (suite *HealthHandlerUnitTestsSuite) TestHTTPReachabilityCheck() {
+	status := http.StatusMethodNotAllowed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	check := HTTPReachabilityCheck(server.Client(), server.URL)
+
+	assert.NoError(suite.T(), check(context.Background()))
+
+	status = http.StatusBadGateway
+	assert.Error(suite.T(), check(context.Background()))
+
+	server.Close()
+	assert.Error(suite.T(), check(context.Background()))
+}
@@ -1,39 +1,101 @@
 package handler
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"notifications-ms/src/dto"
+	"notifications-ms/src/model"
+	"notifications-ms/src/realtime"
 	"notifications-ms/src/service"
 	"notifications-ms/src/utils"
-	"os"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
 )
 
 type NotificationHandler struct {
-	Service *service.NotificationService
-	Logger  *logrus.Entry
+	Service           *service.NotificationService
+	Hub               *realtime.NotificationHub
+	HeartbeatInterval time.Duration
+	Logger            *logrus.Entry
 }
 
-func extractClaims(tokenStr string) (jwt.MapClaims, bool) {
-	token, _ := jwt.Parse(strings.Split(tokenStr, " ")[1], nil)
+func getNotificationFilter(ctx *gin.Context) (*model.NotificationFilter, error) {
+	var filter model.NotificationFilter
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		return claims, true
-	} else {
-		fmt.Println("Invalid JWT Token")
-		return nil, false
+	if since := ctx.Query("since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return nil, errors.New("since should be an RFC3339 timestamp")
+		}
+		filter.Since = &parsed
 	}
+
+	if until := ctx.Query("until"); until != "" {
+		parsed, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, errors.New("until should be an RFC3339 timestamp")
+		}
+		filter.Until = &parsed
+	}
+
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		return nil, errors.New("since should be before until")
+	}
+
+	types, err := getNotificationTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter.Types = types
+
+	return &filter, nil
+}
+
+func getNotificationDeleteFilter(ctx *gin.Context) (*model.NotificationDeleteFilter, error) {
+	var filter model.NotificationDeleteFilter
+
+	for _, idParam := range ctx.QueryArray("id") {
+		id, err := getId(idParam)
+		if err != nil {
+			return nil, err
+		}
+		filter.IDs = append(filter.IDs, id)
+	}
+
+	types, err := getNotificationTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter.Types = types
+
+	if olderThan := ctx.Query("olderThan"); olderThan != "" {
+		parsed, err := time.Parse(time.RFC3339, olderThan)
+		if err != nil {
+			return nil, errors.New("olderThan should be an RFC3339 timestamp")
+		}
+		filter.OlderThan = &parsed
+	}
+
+	return &filter, nil
+}
+
+func getNotificationTypes(ctx *gin.Context) ([]model.NotificationType, error) {
+	var types []model.NotificationType
+
+	for _, typeParam := range ctx.QueryArray("type") {
+		notificationType, err := strconv.Atoi(typeParam)
+		if err != nil || notificationType < int(model.Message) || notificationType > int(model.Comment) {
+			return nil, errors.New("type should be a valid notification type")
+		}
+		types = append(types, model.NotificationType(notificationType))
+	}
+
+	return types, nil
 }
 
 func getId(idParam string) (int, error) {
@@ -48,45 +110,168 @@ func (handler *NotificationHandler) GetNotifications(ctx *gin.Context) {
 	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "GET /notifications")
 	defer span.Finish()
 
-	claims, _ := extractClaims(ctx.Request.Header.Get("Authorization"))
+	principal := getPrincipal(ctx)
+
+	filter, err := getNotificationFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := 0
+	if limitParam := ctx.Query("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit should be a positive number"})
+			return
+		}
+		limit = parsed
+	}
 
-	handler.Logger.Info(fmt.Sprintf("Getting notifications for user %s", fmt.Sprint(claims["sub"])))
-	notifications := handler.Service.GetNotifications(fmt.Sprint(claims["sub"]))
+	handler.Logger.Info(fmt.Sprintf("Getting notifications for user %s", principal.Subject))
+	page, err := handler.Service.GetNotificationsPage(principal.Subject, filter, limit, ctx.Query("cursor"))
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	ctx.JSON(http.StatusOK, notifications)
+	ctx.JSON(http.StatusOK, page)
 }
 
 func (handler *NotificationHandler) DeleteNotifications(ctx *gin.Context) {
 	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "DELETE /notifications")
 	defer span.Finish()
 
-	claims, _ := extractClaims(ctx.Request.Header.Get("Authorization"))
+	filter, err := getNotificationDeleteFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal := getPrincipal(ctx)
+
+	handler.Logger.Info(fmt.Sprintf("Deleting notifications for user %s", principal.Subject))
+	count, err := handler.Service.DeleteNotifications(principal.Subject, filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"deleted": count})
+}
+
+func (handler *NotificationHandler) DeleteNotification(ctx *gin.Context) {
+	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "DELETE /notifications/:id")
+	defer span.Finish()
+
+	id, err := getId(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	handler.Logger.Info(fmt.Sprintf("Deleting notifications for user %s", fmt.Sprint(claims["sub"])))
-	handler.Service.DeleteNotifications(fmt.Sprint(claims["sub"]))
+	principal := getPrincipal(ctx)
+
+	handler.Logger.Info(fmt.Sprintf("Deleting notification %d for user %s", id, principal.Subject))
+	err = handler.Service.DeleteNotification(id, principal.Subject)
+	if errors.Is(err, service.ErrNotificationNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	AddSystemEvent(time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf("Notifications deleted for user %s", fmt.Sprint(claims["sub"])))
 	ctx.JSON(http.StatusOK, nil)
 }
 
-func AddSystemEvent(time string, message string) error {
-	logger := utils.Logger()
-	event := dto.EventRequestDTO{
-		Timestamp: time,
-		Message:   message,
+func (handler *NotificationHandler) MarkNotificationAsRead(ctx *gin.Context) {
+	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "PUT /notifications/:id/read")
+	defer span.Finish()
+
+	id, err := getId(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal := getPrincipal(ctx)
+
+	handler.Logger.Info(fmt.Sprintf("Marking notification %d as read for user %s", id, principal.Subject))
+	err = handler.Service.MarkAsRead(id, principal.Subject)
+	if errors.Is(err, service.ErrNotificationNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+func (handler *NotificationHandler) MarkNotificationsAsRead(ctx *gin.Context) {
+	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "PUT /notifications/read")
+	defer span.Finish()
+
+	var request dto.MarkAsReadRequestDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal := getPrincipal(ctx)
+
+	handler.Logger.Info(fmt.Sprintf("Marking notifications as read for user %s", principal.Subject))
+	count, err := handler.Service.MarkManyAsRead(request.IDs, principal.Subject)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"updated": count})
+}
+
+func (handler *NotificationHandler) MarkAllNotificationsAsRead(ctx *gin.Context) {
+	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "PUT /notifications/read-all")
+	defer span.Finish()
+
+	principal := getPrincipal(ctx)
+
+	handler.Logger.Info(fmt.Sprintf("Marking all notifications as read for user %s", principal.Subject))
+	count, err := handler.Service.MarkAllAsRead(principal.Subject)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"updated": count})
+}
+
+func (handler *NotificationHandler) GetUnreadCount(ctx *gin.Context) {
+	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "GET /notifications/unread-count")
+	defer span.Finish()
+
+	byType, err := strconv.ParseBool(ctx.DefaultQuery("byType", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "byType should be a boolean"})
+		return
 	}
 
-	b, _ := json.Marshal(&event)
-	endpoint := os.Getenv("EVENTS_MS")
-	logger.Info("Sending system event to events-ms")
-	req, _ := http.NewRequest("POST", endpoint, bytes.NewBuffer(b))
-	req.Header.Set("content-type", "application/json")
+	principal := getPrincipal(ctx)
 
-	_, err := http.DefaultClient.Do(req)
+	handler.Logger.Info(fmt.Sprintf("Getting unread notification count for user %s", principal.Subject))
+	count, err := handler.Service.GetUnreadCount(principal.Subject, byType)
 	if err != nil {
-		logger.Debug("Error happened during sending system event")
-		return err
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	return nil
+	ctx.JSON(http.StatusOK, count)
 }
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"notifications-ms/src/auth"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"notifications-ms/src/service"
+	"notifications-ms/src/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationHandlerUnitTestsSuite struct {
+	suite.Suite
+	repositoryMock *repository.NotificationRepositoryMock
+	router         *gin.Engine
+}
+
+func TestNotificationHandlerUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(NotificationHandlerUnitTestsSuite))
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.repositoryMock = new(repository.NotificationRepositoryMock)
+	suite.repositoryMock.Outbox.On("AddEvents", mock.Anything).Return(nil)
+
+	notificationHandler := NotificationHandler{
+		Service: &service.NotificationService{NotificationRepo: suite.repositoryMock, Logger: utils.Logger()},
+		Logger:  utils.Logger(),
+	}
+
+	suite.router = gin.New()
+	notifications := suite.router.Group("/notifications", func(ctx *gin.Context) {
+		ctx.Set(principalKey, &auth.Principal{Subject: "auth0|1"})
+	})
+	notifications.DELETE("", notificationHandler.DeleteNotifications)
+	notifications.DELETE("/:id", notificationHandler.DeleteNotification)
+	notifications.GET("/unread-count", notificationHandler.GetUnreadCount)
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) request(method, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	return recorder
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) assertSystemEvent(message string) {
+	suite.repositoryMock.Outbox.AssertCalled(suite.T(), "AddEvents", mock.MatchedBy(func(events []*model.OutboxEvent) bool {
+		return len(events) == 1 && events[0].Message == message
+	}))
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) TestDeleteNotifications_WithoutFilterDeletesAll() {
+	suite.repositoryMock.On("DeleteNotificationsByUserAuth0ID", "auth0|1", &model.NotificationDeleteFilter{}).Return(int64(3), nil).Once()
+
+	recorder := suite.request(http.MethodDelete, "/notifications")
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+	assert.JSONEq(suite.T(), `{"deleted":3}`, recorder.Body.String())
+
+	suite.assertSystemEvent("3 notifications deleted for user auth0|1")
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) TestDeleteNotifications_WithFilters() {
+	olderThan, _ := time.Parse(time.RFC3339, "2022-06-01T00:00:00Z")
+	filter := &model.NotificationDeleteFilter{
+		IDs:       []int{4, 7},
+		Types:     []model.NotificationType{model.Like},
+		OlderThan: &olderThan,
+	}
+	suite.repositoryMock.On("DeleteNotificationsByUserAuth0ID", "auth0|1", filter).Return(int64(0), nil).Once()
+
+	recorder := suite.request(http.MethodDelete, "/notifications?id=4&id=7&type=2&olderThan=2022-06-01T00:00:00Z")
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+	assert.JSONEq(suite.T(), `{"deleted":0}`, recorder.Body.String())
+	suite.repositoryMock.Outbox.AssertNotCalled(suite.T(), "AddEvents", mock.Anything)
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) TestDeleteNotifications_InvalidFilters() {
+	for _, query := range []string{"id=abc", "type=9", "olderThan=yesterday"} {
+		recorder := suite.request(http.MethodDelete, "/notifications?"+query)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, recorder.Code, query)
+	}
+	suite.repositoryMock.AssertNotCalled(suite.T(), "DeleteNotificationsByUserAuth0ID", mock.Anything, mock.Anything)
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) TestDeleteNotification_OwnNotification() {
+	suite.repositoryMock.On("DeleteNotification", 5, "auth0|1").Return(nil).Once()
+
+	recorder := suite.request(http.MethodDelete, "/notifications/5")
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+	suite.assertSystemEvent("Notification 5 deleted for user auth0|1")
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) TestDeleteNotification_ForeignOrMissingNotification() {
+	suite.repositoryMock.On("DeleteNotification", 6, "auth0|1").Return(gorm.ErrRecordNotFound).Once()
+
+	recorder := suite.request(http.MethodDelete, "/notifications/6")
+
+	assert.Equal(suite.T(), http.StatusNotFound, recorder.Code)
+	assert.JSONEq(suite.T(), `{"error":"notification not found"}`, recorder.Body.String())
+	suite.repositoryMock.Outbox.AssertNotCalled(suite.T(), "AddEvents", mock.Anything)
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) TestDeleteNotification_InvalidID() {
+	recorder := suite.request(http.MethodDelete, "/notifications/abc")
+
+	assert.Equal(suite.T(), http.StatusBadRequest, recorder.Code)
+	suite.repositoryMock.AssertNotCalled(suite.T(), "DeleteNotification", mock.Anything, mock.Anything)
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) TestDeleteNotification_RepositoryError() {
+	suite.repositoryMock.On("DeleteNotification", 8, "auth0|1").Return(gorm.ErrInvalidSQL).Once()
+
+	recorder := suite.request(http.MethodDelete, "/notifications/8")
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, recorder.Code)
+}
+
+func (suite *NotificationHandlerUnitTestsSuite) TestGetUnreadCount_ByTypeIsAListOfTypeCounts() {
+	suite.repositoryMock.On("CountUnreadNotificationsByType", "auth0|1").Return(map[model.NotificationType]int64{model.Like: 3, model.Follow: 2}, nil).Once()
+
+	recorder := suite.request(http.MethodGet, "/notifications/unread-count?byType=true")
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+	assert.JSONEq(suite.T(), `{"Total":5,"ByType":[{"Type":1,"Count":2},{"Type":2,"Count":3}]}`, recorder.Body.String())
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"notifications-ms/src/dto"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	socketWriteWait      = 10 * time.Second
+	socketPongWait       = 60 * time.Second
+	socketPingPeriod     = (socketPongWait * 9) / 10
+	socketMaxMessageSize = 4096
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// CORS is open for the whole API, see main.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func (handler *NotificationHandler) NotificationSocket(ctx *gin.Context) {
+	principal := getPrincipal(ctx)
+
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		handler.Logger.Debug(err.Error())
+		return
+	}
+	defer conn.Close()
+
+	subscription := handler.Hub.Subscribe(principal.Subject)
+	defer handler.Hub.Unsubscribe(subscription)
+
+	handler.Logger.Info(fmt.Sprintf("Opening notification socket for user %s", principal.Subject))
+
+	replies := make(chan dto.SocketMessageDTO, 16)
+	done := make(chan struct{})
+	closing := make(chan struct{})
+	defer close(closing)
+
+	go handler.readSocketCommands(conn, principal.Subject, replies, done, closing)
+
+	ping := time.NewTicker(socketPingPeriod)
+	defer ping.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-done:
+			handler.Logger.Info(fmt.Sprintf("Notification socket closed by user %s", principal.Subject))
+			return
+		case notification, ok := <-subscription.C:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber too slow"), time.Now().Add(socketWriteWait))
+				return
+			}
+			err = writeSocketMessage(conn, dto.SocketMessageDTO{Type: dto.SocketMessageNotification, Notification: notification})
+		case reply := <-replies:
+			err = writeSocketMessage(conn, reply)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteWait))
+		}
+
+		if err != nil {
+			handler.Logger.Debug(err.Error())
+			return
+		}
+	}
+}
+
+func (handler *NotificationHandler) readSocketCommands(conn *websocket.Conn, userAuth0ID string, replies chan<- dto.SocketMessageDTO, done chan<- struct{}, closing <-chan struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(socketMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var reply dto.SocketMessageDTO
+		var command dto.SocketCommandDTO
+		if err := json.Unmarshal(message, &command); err != nil {
+			reply = dto.SocketMessageDTO{Type: dto.SocketMessageError, Error: "malformed command"}
+		} else {
+			reply = handler.executeSocketCommand(userAuth0ID, &command)
+		}
+
+		select {
+		case replies <- reply:
+		case <-closing:
+			return
+		}
+	}
+}
+
+func (handler *NotificationHandler) executeSocketCommand(userAuth0ID string, command *dto.SocketCommandDTO) dto.SocketMessageDTO {
+	var affected int64
+	var err error
+
+	switch command.Type {
+	case dto.SocketCommandRead:
+		affected, err = handler.Service.MarkManyAsRead(command.IDs, userAuth0ID)
+	case dto.SocketCommandReadAll:
+		affected, err = handler.Service.MarkAllAsRead(userAuth0ID)
+	case dto.SocketCommandDelete:
+		for _, id := range command.IDs {
+			if err = handler.Service.DeleteNotification(id, userAuth0ID); err != nil {
+				break
+			}
+			affected++
+		}
+	default:
+		err = fmt.Errorf("unknown command %q", command.Type)
+	}
+
+	if err != nil {
+		return dto.SocketMessageDTO{Type: dto.SocketMessageError, RequestID: command.RequestID, Affected: &affected, Error: err.Error()}
+	}
+
+	return dto.SocketMessageDTO{Type: dto.SocketMessageAck, RequestID: command.RequestID, Affected: &affected}
+}
+
+func writeSocketMessage(conn *websocket.Conn, message dto.SocketMessageDTO) error {
+	conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return conn.WriteJSON(message)
+}
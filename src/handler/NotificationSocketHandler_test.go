@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"notifications-ms/src/auth"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/realtime"
+	"notifications-ms/src/repository"
+	"notifications-ms/src/service"
+	"notifications-ms/src/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationSocketUnitTestsSuite struct {
+	suite.Suite
+	repositoryMock *repository.NotificationRepositoryMock
+	hub            *realtime.NotificationHub
+	server         *httptest.Server
+	conn           *websocket.Conn
+}
+
+func TestNotificationSocketUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(NotificationSocketUnitTestsSuite))
+}
+
+func (suite *NotificationSocketUnitTestsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.repositoryMock = new(repository.NotificationRepositoryMock)
+	suite.hub = realtime.NewNotificationHub()
+
+	notificationHandler := NotificationHandler{
+		Service: &service.NotificationService{NotificationRepo: suite.repositoryMock, Logger: utils.Logger()},
+		Hub:     suite.hub,
+		Logger:  utils.Logger(),
+	}
+
+	router := gin.New()
+	router.GET("/notifications/ws", func(ctx *gin.Context) {
+		ctx.Set(principalKey, &auth.Principal{Subject: "auth0|1"})
+	}, notificationHandler.NotificationSocket)
+
+	suite.server = httptest.NewServer(router)
+
+	url := "ws" + strings.TrimPrefix(suite.server.URL, "http") + "/notifications/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	suite.Require().Nil(err)
+	suite.conn = conn
+}
+
+func (suite *NotificationSocketUnitTestsSuite) TearDownTest() {
+	suite.conn.Close()
+	suite.server.Close()
+}
+
+func (suite *NotificationSocketUnitTestsSuite) readMessage() dto.SocketMessageDTO {
+	var message dto.SocketMessageDTO
+	suite.conn.SetReadDeadline(time.Now().Add(time.Second))
+	suite.Require().Nil(suite.conn.ReadJSON(&message))
+	return message
+}
+
+func (suite *NotificationSocketUnitTestsSuite) TestNotificationSocket_PublishedNotificationIsPushed() {
+	assert.Eventually(suite.T(), func() bool {
+		return suite.hub.SubscriberCount("auth0|1") == 1
+	}, time.Second, 10*time.Millisecond)
+
+	suite.hub.Publish(&dto.NotificationDTO{ID: 9, UserAuth0ID: "auth0|1", Message: "Hello"})
+
+	message := suite.readMessage()
+
+	assert.Equal(suite.T(), dto.SocketMessageNotification, message.Type)
+	assert.Equal(suite.T(), 9, message.Notification.ID)
+}
+
+func (suite *NotificationSocketUnitTestsSuite) TestNotificationSocket_ReadCommandIsAcknowledged() {
+	suite.repositoryMock.On("MarkNotificationsAsRead", []int{1, 2}, "auth0|1").Return(int64(2), nil).Once()
+
+	suite.conn.WriteJSON(dto.SocketCommandDTO{Type: dto.SocketCommandRead, RequestID: "r1", IDs: []int{1, 2}})
+	message := suite.readMessage()
+
+	assert.Equal(suite.T(), dto.SocketMessageAck, message.Type)
+	assert.Equal(suite.T(), "r1", message.RequestID)
+	assert.Equal(suite.T(), int64(2), *message.Affected)
+}
+
+func (suite *NotificationSocketUnitTestsSuite) TestNotificationSocket_DeleteOfForeignNotificationFails() {
+	suite.repositoryMock.On("DeleteNotification", 5, "auth0|1").Return(gorm.ErrRecordNotFound).Once()
+
+	suite.conn.WriteJSON(dto.SocketCommandDTO{Type: dto.SocketCommandDelete, RequestID: "d1", IDs: []int{5}})
+	message := suite.readMessage()
+
+	assert.Equal(suite.T(), dto.SocketMessageError, message.Type)
+	assert.Equal(suite.T(), "d1", message.RequestID)
+	assert.Equal(suite.T(), service.ErrNotificationNotFound.Error(), message.Error)
+}
+
+func (suite *NotificationSocketUnitTestsSuite) TestNotificationSocket_UnknownCommandFails() {
+	suite.conn.WriteJSON(dto.SocketCommandDTO{Type: "shout", RequestID: "u1"})
+	message := suite.readMessage()
+
+	assert.Equal(suite.T(), dto.SocketMessageError, message.Type)
+	assert.Equal(suite.T(), "u1", message.RequestID)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+)
+
+const DefaultHeartbeatInterval = 15 * time.Second
+
+func (handler *NotificationHandler) StreamNotifications(ctx *gin.Context) {
+	span, _ := opentracing.StartSpanFromContext(ctx.Request.Context(), "GET /notifications/stream")
+	defer span.Finish()
+
+	principal := getPrincipal(ctx)
+
+	lastID := 0
+	if lastEventID := ctx.GetHeader("Last-Event-ID"); lastEventID != "" {
+		id, err := getId(lastEventID)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID should be a number"})
+			return
+		}
+		lastID = id
+	}
+
+	subscription := handler.Hub.Subscribe(principal.Subject)
+	defer handler.Hub.Unsubscribe(subscription)
+
+	handler.Logger.Info(fmt.Sprintf("Opening notification stream for user %s", principal.Subject))
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	// The subscription is open while the backlog is replayed, so a replayed
+	// notification may come in live as well; only those are skipped.
+	replayed := map[int]bool{}
+	for afterID := lastID; afterID > 0; {
+		missed, err := handler.Service.GetNotificationsAfter(principal.Subject, afterID)
+		if err != nil {
+			handler.Logger.Debug(err.Error())
+			return
+		}
+		for _, notification := range missed {
+			writeNotificationEvent(ctx.Writer, notification)
+			replayed[notification.ID] = true
+			afterID = notification.ID
+		}
+		ctx.Writer.Flush()
+		if len(missed) < service.ResumePageSize {
+			break
+		}
+	}
+	ctx.Writer.Flush()
+
+	heartbeatInterval := handler.HeartbeatInterval
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = DefaultHeartbeatInterval
+	}
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			handler.Logger.Info(fmt.Sprintf("Notification stream closed by user %s", principal.Subject))
+			return
+		case notification, ok := <-subscription.C:
+			if !ok {
+				return
+			}
+			if replayed[notification.ID] {
+				delete(replayed, notification.ID)
+				continue
+			}
+			writeNotificationEvent(ctx.Writer, notification)
+		case <-heartbeat.C:
+			io.WriteString(ctx.Writer, ": heartbeat\n\n")
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+func writeNotificationEvent(w io.Writer, notification *dto.NotificationDTO) {
+	sse.Encode(w, sse.Event{
+		Id:    strconv.Itoa(notification.ID),
+		Event: "notification",
+		Data:  notification,
+	})
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"notifications-ms/src/auth"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/model"
+	"notifications-ms/src/realtime"
+	"notifications-ms/src/repository"
+	"notifications-ms/src/service"
+	"notifications-ms/src/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationStreamUnitTestsSuite struct {
+	suite.Suite
+	repositoryMock *repository.NotificationRepositoryMock
+	hub            *realtime.NotificationHub
+	server         *httptest.Server
+}
+
+func TestNotificationStreamUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(NotificationStreamUnitTestsSuite))
+}
+
+func (suite *NotificationStreamUnitTestsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.repositoryMock = new(repository.NotificationRepositoryMock)
+	suite.hub = realtime.NewNotificationHub()
+
+	notificationHandler := NotificationHandler{
+		Service:           &service.NotificationService{NotificationRepo: suite.repositoryMock, Logger: utils.Logger()},
+		Hub:               suite.hub,
+		HeartbeatInterval: 50 * time.Millisecond,
+		Logger:            utils.Logger(),
+	}
+
+	router := gin.New()
+	router.GET("/notifications/stream", func(ctx *gin.Context) {
+		ctx.Set(principalKey, &auth.Principal{Subject: "auth0|1"})
+	}, notificationHandler.StreamNotifications)
+
+	suite.server = httptest.NewServer(router)
+}
+
+func (suite *NotificationStreamUnitTestsSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *NotificationStreamUnitTestsSuite) open(lastEventID string) (*bufio.Reader, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, suite.server.URL+"/notifications/stream", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	response, err := http.DefaultClient.Do(req)
+	suite.Require().Nil(err)
+	suite.Require().Equal(http.StatusOK, response.StatusCode)
+
+	return bufio.NewReader(response.Body), cancel
+}
+
+func readEvent(reader *bufio.Reader) string {
+	var event strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil || line == "\n" {
+			return event.String()
+		}
+		event.WriteString(line)
+	}
+}
+
+func (suite *NotificationStreamUnitTestsSuite) waitForSubscriber() {
+	assert.Eventually(suite.T(), func() bool {
+		return suite.hub.SubscriberCount("auth0|1") == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (suite *NotificationStreamUnitTestsSuite) TestStreamNotifications_PublishedNotificationIsDelivered() {
+	reader, cancel := suite.open("")
+	defer cancel()
+	suite.waitForSubscriber()
+
+	suite.hub.Publish(&dto.NotificationDTO{ID: 3, UserAuth0ID: "auth0|1", Message: "Hello"})
+
+	event := readEvent(reader)
+	for strings.HasPrefix(event, ":") {
+		event = readEvent(reader)
+	}
+
+	assert.Contains(suite.T(), event, "id:3\n")
+	assert.Contains(suite.T(), event, "event:notification\n")
+	assert.Contains(suite.T(), event, `"Message":"Hello"`)
+}
+
+func (suite *NotificationStreamUnitTestsSuite) TestStreamNotifications_ResumesFromLastEventID() {
+	ntype := model.Like
+	missed := []*model.Notification{
+		{ID: 6, Message: "Missed", UserAuth0ID: "auth0|1", NotificationType: &ntype},
+	}
+	suite.repositoryMock.On("GetNotificationsAfterID", "auth0|1", 5, service.ResumePageSize).Return(missed, nil).Once()
+
+	reader, cancel := suite.open("5")
+	defer cancel()
+
+	event := readEvent(reader)
+
+	assert.Contains(suite.T(), event, "id:6\n")
+	assert.Contains(suite.T(), event, `"Message":"Missed"`)
+}
+
+func (suite *NotificationStreamUnitTestsSuite) TestStreamNotifications_PagesThroughLongBacklog() {
+	ntype := model.Like
+	firstPage := make([]*model.Notification, service.ResumePageSize)
+	for i := range firstPage {
+		firstPage[i] = &model.Notification{ID: 6 + i, Message: "Missed", UserAuth0ID: "auth0|1", NotificationType: &ntype}
+	}
+	lastOfFirstPage := firstPage[len(firstPage)-1].ID
+	secondPage := []*model.Notification{
+		{ID: lastOfFirstPage + 1, Message: "Last missed", UserAuth0ID: "auth0|1", NotificationType: &ntype},
+	}
+	suite.repositoryMock.On("GetNotificationsAfterID", "auth0|1", 5, service.ResumePageSize).Return(firstPage, nil).Once()
+	suite.repositoryMock.On("GetNotificationsAfterID", "auth0|1", lastOfFirstPage, service.ResumePageSize).Return(secondPage, nil).Once()
+
+	reader, cancel := suite.open("5")
+	defer cancel()
+
+	for i := 0; i < service.ResumePageSize; i++ {
+		readEvent(reader)
+	}
+	event := readEvent(reader)
+
+	assert.Contains(suite.T(), event, fmt.Sprintf("id:%d\n", lastOfFirstPage+1))
+	assert.Contains(suite.T(), event, `"Message":"Last missed"`)
+}
+
+func (suite *NotificationStreamUnitTestsSuite) TestStreamNotifications_SkipsOnlyReplayedLiveNotifications() {
+	ntype := model.Like
+	missed := []*model.Notification{
+		{ID: 6, Message: "Missed", UserAuth0ID: "auth0|1", NotificationType: &ntype},
+	}
+	suite.repositoryMock.On("GetNotificationsAfterID", "auth0|1", 5, service.ResumePageSize).Return(missed, nil).Run(func(args mock.Arguments) {
+		suite.hub.Publish(&dto.NotificationDTO{ID: 6, UserAuth0ID: "auth0|1", Message: "Missed"})
+	}).Once()
+
+	reader, cancel := suite.open("5")
+	defer cancel()
+
+	assert.Contains(suite.T(), readEvent(reader), "id:6\n")
+
+	// Committed before ID 6 but published after it was replayed.
+	suite.hub.Publish(&dto.NotificationDTO{ID: 4, UserAuth0ID: "auth0|1", Message: "Late"})
+
+	event := readEvent(reader)
+	for strings.HasPrefix(event, ":") {
+		event = readEvent(reader)
+	}
+
+	assert.Contains(suite.T(), event, "id:4\n")
+	assert.Contains(suite.T(), event, `"Message":"Late"`)
+}
+
+func (suite *NotificationStreamUnitTestsSuite) TestStreamNotifications_SendsHeartbeats() {
+	reader, cancel := suite.open("")
+	defer cancel()
+
+	event := readEvent(reader)
+
+	assert.Equal(suite.T(), ": heartbeat\n", event)
+}
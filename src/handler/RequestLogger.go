@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger logs requests the way gin.Logger does, but without the
+// access_token query parameter that QueryTokenAuthMiddleware accepts, so
+// stream and socket tokens never end up in the logs.
+func RequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(params gin.LogFormatterParams) string {
+		params.Path = stripAccessToken(params.Path)
+		return formatRequest(params)
+	})
+}
+
+func stripAccessToken(path string) string {
+	index := strings.IndexByte(path, '?')
+	if index < 0 {
+		return path
+	}
+
+	query, err := url.ParseQuery(path[index+1:])
+	if err != nil {
+		return path[:index]
+	}
+	if _, ok := query["access_token"]; !ok {
+		return path
+	}
+
+	query.Del("access_token")
+	if len(query) == 0 {
+		return path[:index]
+	}
+	return path[:index] + "?" + query.Encode()
+}
+
+func formatRequest(params gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if params.IsOutputColor() {
+		statusColor = params.StatusCodeColor()
+		methodColor = params.MethodColor()
+		resetColor = params.ResetColor()
+	}
+
+	if params.Latency > time.Minute {
+		params.Latency = params.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		params.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, params.StatusCode, resetColor,
+		params.Latency,
+		params.ClientIP,
+		methodColor, params.Method, resetColor,
+		params.Path,
+		params.ErrorMessage,
+	)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RequestLoggerUnitTestsSuite struct {
+	suite.Suite
+	output *bytes.Buffer
+	router *gin.Engine
+}
+
+func TestRequestLoggerUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(RequestLoggerUnitTestsSuite))
+}
+
+func (suite *RequestLoggerUnitTestsSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.output = new(bytes.Buffer)
+
+	writer := gin.DefaultWriter
+	gin.DefaultWriter = suite.output
+	defer func() { gin.DefaultWriter = writer }()
+
+	suite.router = gin.New()
+	suite.router.Use(RequestLogger())
+	suite.router.GET("/notifications/stream", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+}
+
+func (suite *RequestLoggerUnitTestsSuite) TestRequestLogger_StripsAccessToken() {
+	suite.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/notifications/stream?access_token=secret.jwt.value&after=5", nil))
+
+	assert.Contains(suite.T(), suite.output.String(), `"/notifications/stream?after=5"`)
+	assert.NotContains(suite.T(), suite.output.String(), "secret.jwt.value")
+}
+
+func (suite *RequestLoggerUnitTestsSuite) TestStripAccessToken() {
+	assert.Equal(suite.T(), "/notifications/ws", stripAccessToken("/notifications/ws?access_token=abc"))
+	assert.Equal(suite.T(), "/notifications?limit=5", stripAccessToken("/notifications?limit=5"))
+	assert.Equal(suite.T(), "/notifications", stripAccessToken("/notifications"))
+}
@@ -0,0 +1,73 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type hook struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Lifecycle stops the parts of the application in the reverse order they were
+// registered in, all within one shared deadline.
+type Lifecycle struct {
+	Logger *logrus.Entry
+
+	mu    sync.Mutex
+	hooks []hook
+}
+
+func New(logger *logrus.Entry) *Lifecycle {
+	return &Lifecycle{Logger: logger}
+}
+
+func (lifecycle *Lifecycle) OnStop(name string, stop func(ctx context.Context) error) {
+	lifecycle.mu.Lock()
+	defer lifecycle.mu.Unlock()
+
+	lifecycle.hooks = append(lifecycle.hooks, hook{name: name, stop: stop})
+}
+
+// Shutdown runs every hook even if an earlier one failed or the deadline
+// passed, and returns the first error.
+func (lifecycle *Lifecycle) Shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	lifecycle.mu.Lock()
+	hooks := lifecycle.hooks
+	lifecycle.hooks = nil
+	lifecycle.mu.Unlock()
+
+	var firstErr error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		lifecycle.Logger.Info(fmt.Sprintf("Stopping %s", hooks[i].name))
+		if err := hooks[i].stop(ctx); err != nil {
+			lifecycle.Logger.Error(fmt.Sprintf("Failed to stop %s: %v", hooks[i].name, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// WaitFor adapts a done channel, such as that of a background worker, to a
+// stop hook that gives up at the shutdown deadline.
+func WaitFor(done <-chan struct{}) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
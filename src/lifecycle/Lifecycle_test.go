@@ -0,0 +1,77 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"notifications-ms/src/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LifecycleUnitTestsSuite struct {
+	suite.Suite
+	lifecycle *Lifecycle
+}
+
+func TestLifecycleUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(LifecycleUnitTestsSuite))
+}
+
+func (suite *LifecycleUnitTestsSuite) SetupTest() {
+	suite.lifecycle = New(utils.Logger())
+}
+
+func (suite *LifecycleUnitTestsSuite) TestShutdown_StopsInReverseOrder() {
+	var stopped []string
+	for _, name := range []string{"database", "consumer", "http server"} {
+		name := name
+		suite.lifecycle.OnStop(name, func(ctx context.Context) error {
+			stopped = append(stopped, name)
+			return nil
+		})
+	}
+
+	err := suite.lifecycle.Shutdown(time.Second)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"http server", "consumer", "database"}, stopped)
+}
+
+func (suite *LifecycleUnitTestsSuite) TestShutdown_FailingHookDoesNotStopOthers() {
+	databaseClosed := false
+	suite.lifecycle.OnStop("database", func(ctx context.Context) error {
+		databaseClosed = true
+		return nil
+	})
+	suite.lifecycle.OnStop("http server", func(ctx context.Context) error {
+		return errors.New("shutdown failed")
+	})
+
+	err := suite.lifecycle.Shutdown(time.Second)
+
+	assert.EqualError(suite.T(), err, "shutdown failed")
+	assert.True(suite.T(), databaseClosed)
+}
+
+func (suite *LifecycleUnitTestsSuite) TestShutdown_WaitForGivesUpAtDeadline() {
+	suite.lifecycle.OnStop("consumer", WaitFor(make(chan struct{})))
+
+	start := time.Now()
+	err := suite.lifecycle.Shutdown(20 * time.Millisecond)
+
+	assert.ErrorIs(suite.T(), err, context.DeadlineExceeded)
+	assert.Less(suite.T(), time.Since(start), time.Second)
+}
+
+func (suite *LifecycleUnitTestsSuite) TestShutdown_WaitForReturnsWhenDone() {
+	done := make(chan struct{})
+	close(done)
+	suite.lifecycle.OnStop("consumer", WaitFor(done))
+
+	err := suite.lifecycle.Shutdown(time.Second)
+
+	assert.Nil(suite.T(), err)
+}
@@ -1,17 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"notifications-ms/src/auth"
+	"notifications-ms/src/config"
+	"notifications-ms/src/email"
+	"notifications-ms/src/events"
 	"notifications-ms/src/handler"
-	"notifications-ms/src/model"
+	"notifications-ms/src/lifecycle"
+	"notifications-ms/src/outbox"
+	"notifications-ms/src/push"
 	"notifications-ms/src/rabbitmq"
+	"notifications-ms/src/realtime"
 	"notifications-ms/src/repository"
 	"notifications-ms/src/service"
 	"notifications-ms/src/utils"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -23,69 +35,186 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 	"github.com/uber/jaeger-client-go"
-	"github.com/uber/jaeger-client-go/config"
+	jaegercfg "github.com/uber/jaeger-client-go/config"
 )
 
-var db *gorm.DB
-var err error
-
-func initDB() (*gorm.DB, error) {
-	host := os.Getenv("DATABASE_DOMAIN")
-	user := os.Getenv("DATABASE_USERNAME")
-	password := os.Getenv("DATABASE_PASSWORD")
-	name := os.Getenv("DATABASE_SCHEMA")
-	port := os.Getenv("DATABASE_PORT")
-
-	connectionString := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		host,
-		user,
-		password,
-		name,
-		port,
-	)
-	db, _ = gorm.Open("postgres", connectionString)
+func initDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := gorm.Open("postgres", cfg.ConnectionString())
 
 	if err != nil {
-		panic("failed to connect database")
+		return nil, err
 	}
 
-	db.AutoMigrate(model.Notification{})
-	return db, err
+	db.DB().SetMaxOpenConns(cfg.MaxOpenConns)
+	db.DB().SetMaxIdleConns(cfg.MaxIdleConns)
+	db.DB().SetConnMaxLifetime(cfg.ConnMaxLifetime())
+
+	return db, nil
 }
 
-func InitJaeger() (opentracing.Tracer, io.Closer, error) {
-	cfg := config.Configuration{
-		ServiceName: "notifications-ms",
-		Sampler: &config.SamplerConfig{
+func InitJaeger(cfg config.TracingConfig) (opentracing.Tracer, io.Closer, error) {
+	jaegerCfg := jaegercfg.Configuration{
+		ServiceName: cfg.ServiceName,
+		Sampler: &jaegercfg.SamplerConfig{
 			Type:  "const",
 			Param: 1,
 		},
-		Reporter: &config.ReporterConfig{
-			LogSpans:           true,
-			LocalAgentHostPort: "jaeger:6831",
+		Reporter: &jaegercfg.ReporterConfig{
+			LogSpans:           cfg.LogSpans,
+			LocalAgentHostPort: cfg.AgentHostPort,
 		},
 	}
 
-	tracer, closer, err := cfg.NewTracer(config.Logger(jaeger.StdLogger))
+	tracer, closer, err := jaegerCfg.NewTracer(jaegercfg.Logger(jaeger.StdLogger))
 	return tracer, closer, err
 }
 
+func initTokenVerifier(cfg config.AuthConfig) (*auth.TokenVerifier, error) {
+	if cfg.JWKSURL != "" {
+		return auth.NewTokenVerifier(auth.NewJWKSKeyProvider(cfg.JWKSURL), cfg.Issuer, cfg.Audience), nil
+	}
+
+	keys, err := auth.NewStaticKeyProvider(cfg.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewTokenVerifier(keys, cfg.Issuer, cfg.Audience), nil
+}
+
+func initRetryPolicy(cfg config.AMQPConfig) rabbitmq.RetryPolicy {
+	policy := rabbitmq.DefaultRetryPolicy()
+	policy.MaxAttempts = cfg.RetryMaxAttempts
+	policy.InitialDelay = cfg.RetryInitialDelay()
+	policy.MaxDelay = cfg.RetryMaxDelay()
+	return policy
+}
+
+func initTopology(cfg config.AMQPConfig) rabbitmq.Topology {
+	return rabbitmq.Topology{
+		Exchange:             cfg.Exchange,
+		Queue:                cfg.Queue,
+		RoutingKey:           cfg.RoutingKey,
+		Consumer:             cfg.ConsumerTag,
+		DeadLetterExchange:   cfg.DeadLetterExchange,
+		DeadLetterQueue:      cfg.DeadLetterQueue,
+		DeadLetterRoutingKey: cfg.DeadLetterRoutingKey,
+		RetryExchange:        cfg.RetryExchange,
+	}
+}
+
 func initNotificationRepo(database *gorm.DB) *repository.NotificationRepository {
 	return &repository.NotificationRepository{Database: database}
 }
 
-func initNotificationService(notificationRepo *repository.NotificationRepository) *service.NotificationService {
-	return &service.NotificationService{NotificationRepo: notificationRepo, Logger: utils.Logger()}
+func initNotificationService(notificationRepo *repository.NotificationRepository, publisher service.NotificationPublisher, emailer service.NotificationEmailer) *service.NotificationService {
+	return &service.NotificationService{NotificationRepo: notificationRepo, Publisher: publisher, Emailer: emailer, Logger: utils.Logger()}
+}
+
+func initEmailChannel(cfg config.EmailConfig) *email.Channel {
+	sender := email.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword)
+	sender.Timeout = cfg.SMTPTimeout()
+
+	channel := email.NewChannel(sender, cfg.From, cfg.QueueSize, utils.Logger())
+	channel.Workers = cfg.Workers
+	return channel
+}
+
+func initNotificationHandler(service *service.NotificationService, hub *realtime.NotificationHub) *handler.NotificationHandler {
+	return &handler.NotificationHandler{Service: service, Hub: hub, Logger: utils.Logger()}
+}
+
+func initEventsClientOptions(cfg config.EventsConfig) events.ClientOptions {
+	return events.ClientOptions{
+		RequestTimeout:  cfg.RequestTimeout(),
+		DialTimeout:     cfg.DialTimeout(),
+		MaxIdleConns:    cfg.MaxIdleConns,
+		IdleConnTimeout: cfg.IdleConnTimeout(),
+	}
+}
+
+func initEventsClient(cfg config.EventsConfig) *http.Client {
+	return events.NewClient(initEventsClientOptions(cfg), events.NewCircuitBreaker(cfg.BreakerFailureThreshold, cfg.BreakerOpenTimeout()))
+}
+
+func initEventPublisher(eventsClient *http.Client, cfg *config.Config) events.Publisher {
+	if cfg.Events.Transport == config.EventsTransportAMQP {
+		return events.NewAMQPPublisher(cfg.EventsBrokerURL(), cfg.Events.AMQPExchange, cfg.Events.AMQPQueue, cfg.Events.AMQPRoutingKey)
+	}
+	return events.NewHTTPPublisher(cfg.Events.URL, eventsClient)
+}
+
+func initPushChannel(devices repository.IDeviceRepository, cfg config.PushConfig) *push.Channel {
+	// Only the fake provider exists so far; validation rejects anything else.
+	channel := push.NewChannel(devices, push.NewFakeProvider(utils.Logger()), cfg.QueueSize, utils.Logger())
+	channel.Workers = cfg.Workers
+	channel.SendTimeout = cfg.SendTimeout()
+	return channel
+}
+
+func initDeviceHandler(deviceRepo repository.IDeviceRepository) *handler.DeviceHandler {
+	return &handler.DeviceHandler{Service: &service.DeviceService{DeviceRepo: deviceRepo, Logger: utils.Logger()}, Logger: utils.Logger()}
+}
+
+func initOutboxRelay(database *gorm.DB, publisher events.Publisher, cfg *config.Config) *outbox.Relay {
+	relay := outbox.NewRelay(repository.NewOutboxRepository(database), publisher, utils.Logger())
+	relay.PollInterval = cfg.Outbox.PollInterval()
+	relay.BatchSize = cfg.Outbox.BatchSize
+	relay.DeliveryTimeout = cfg.Outbox.DeliveryTimeout()
+	relay.MaxBackoff = cfg.Outbox.MaxBackoff()
+	return relay
+}
+
+func initHealthHandler(database *gorm.DB, connectionManager *rabbitmq.ConnectionManager, cfg config.EventsConfig) *handler.HealthHandler {
+	checks := []handler.HealthCheck{
+		{
+			Name:     "database",
+			Check:    func(ctx context.Context) error { return database.DB().PingContext(ctx) },
+			Critical: true,
+		},
+		{
+			Name:     "rabbitmq",
+			Check:    connectionManager.Check,
+			Critical: true,
+		},
+	}
+
+	if cfg.HealthCheck {
+		// The probe gets its own client so that it neither trips nor resets
+		// the breaker guarding system event delivery.
+		checks = append(checks, handler.HealthCheck{
+			Name:  "events-ms",
+			Check: handler.HTTPReachabilityCheck(events.NewClient(initEventsClientOptions(cfg), nil), cfg.URL),
+		})
+	}
+
+	return &handler.HealthHandler{Checks: checks}
+}
+
+func handleHealthFunc(healthHandler *handler.HealthHandler, router *gin.Engine) {
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
 }
 
-func initNotificationHandler(service *service.NotificationService) *handler.NotificationHandler {
-	return &handler.NotificationHandler{Service: service, Logger: utils.Logger()}
+func handleDeviceFunc(deviceHandler *handler.DeviceHandler, verifier *auth.TokenVerifier, router *gin.Engine) {
+	devices := router.Group("/devices", handler.AuthMiddleware(verifier, utils.Logger()))
+	devices.GET("", deviceHandler.GetDevices)
+	devices.POST("", deviceHandler.RegisterDevice)
+	devices.DELETE("/:id", deviceHandler.UnregisterDevice)
 }
 
-func handleNotificationFunc(handler *handler.NotificationHandler, router *gin.Engine) {
-	router.GET("/notifications", handler.GetNotifications)
-	router.DELETE("/notifications", handler.DeleteNotifications)
+func handleNotificationFunc(notificationHandler *handler.NotificationHandler, verifier *auth.TokenVerifier, router *gin.Engine) {
+	router.GET("/notifications/stream", handler.QueryTokenAuthMiddleware(verifier, utils.Logger()), notificationHandler.StreamNotifications)
+	router.GET("/notifications/ws", handler.QueryTokenAuthMiddleware(verifier, utils.Logger()), notificationHandler.NotificationSocket)
+
+	notifications := router.Group("/notifications", handler.AuthMiddleware(verifier, utils.Logger()))
+
+	notifications.GET("", notificationHandler.GetNotifications)
+	notifications.GET("/unread-count", notificationHandler.GetUnreadCount)
+	notifications.DELETE("", notificationHandler.DeleteNotifications)
+	notifications.DELETE("/:id", notificationHandler.DeleteNotification)
+	notifications.PUT("/read", notificationHandler.MarkNotificationsAsRead)
+	notifications.PUT("/read-all", notificationHandler.MarkAllNotificationsAsRead)
+	notifications.PUT("/:id/read", notificationHandler.MarkNotificationAsRead)
 }
 
 var totalTrafficSizeInGB = prometheus.NewCounter(
@@ -131,7 +260,12 @@ var uniqueClients = promauto.NewCounterVec(prometheus.CounterOpts{
 
 func prometheusMiddleware() gin.HandlerFunc {
 	return gin.HandlerFunc(func(ctx *gin.Context) {
-		path := ctx.Request.RequestURI
+		// Label by route, not by URI: the URI would add a series per id and
+		// carry the access_token of stream and socket connections.
+		path := ctx.FullPath()
+		if path == "" {
+			path = ctx.Request.URL.Path
+		}
 
 		requestSize := ctx.Request.ContentLength
 
@@ -177,53 +311,157 @@ func prometheusGin() gin.HandlerFunc {
 }
 
 func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or KEY=VALUE configuration file")
+	flag.Parse()
+
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(*configPath, flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	utils.SetLoggerService(cfg.Database.Name)
 	logger := utils.Logger()
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	app := lifecycle.New(logger)
+
 	logger.Info("Connecting with DB")
 
-	database, _ := initDB()
+	database, err := initDB(cfg.Database)
+	if err != nil {
+		logger.Error(err.Error())
+		panic(fmt.Sprintf("failed to connect database: %v", err))
+	}
+
+	if err := checkSchema(database, cfg.Database); err != nil {
+		logger.Error(err.Error())
+		panic(fmt.Sprintf("database schema check failed: %v", err))
+	}
+	app.OnStop("database", func(ctx context.Context) error {
+		return database.Close()
+	})
 
-	port := fmt.Sprintf(":%s", os.Getenv("SERVER_PORT"))
+	if cfg.Tracing.Enabled {
+		logger.Info("Initializing Jaeger")
+		tracer, trCloser, err := InitJaeger(cfg.Tracing)
+		if err != nil {
+			logger.Debug(err.Error())
+			fmt.Printf("error init jaeger %v", err)
+		} else {
+			opentracing.SetGlobalTracer(tracer)
+			app.OnStop("tracer", func(ctx context.Context) error {
+				return trCloser.Close()
+			})
+		}
+	}
 
-	logger.Info("Initializing Jaeger")
-	tracer, trCloser, err := InitJaeger()
+	logger.Info("Initializing token verifier")
+	verifier, err := initTokenVerifier(cfg.Auth)
 	if err != nil {
-		logger.Debug(err.Error())
-		fmt.Printf("error init jaeger %v", err)
-	} else {
-		defer trCloser.Close()
-		opentracing.SetGlobalTracer(tracer)
+		logger.Error(err.Error())
+		panic(fmt.Sprintf("failed to initialize token verifier: %v", err))
 	}
 
-	notificationRepo := initNotificationRepo(database)
-	notificationService := initNotificationService(notificationRepo)
-	notificationHandler := initNotificationHandler(notificationService)
+	notificationHub := realtime.NewNotificationHub()
+	publishers := service.NotificationPublishers{notificationHub}
+
+	var emailer service.NotificationEmailer
+	if cfg.Email.Enabled {
+		logger.Info("Starting email channel")
+		emailChannel := initEmailChannel(cfg.Email)
+		emailCtx, stopEmail := context.WithCancel(context.Background())
+		emailDone := make(chan struct{})
+		go func() {
+			emailChannel.Run(emailCtx)
+			close(emailDone)
+		}()
+		app.OnStop("email channel", func(ctx context.Context) error {
+			stopEmail()
+			return lifecycle.WaitFor(emailDone)(ctx)
+		})
+		emailer = emailChannel
+	}
 
-	amqpServerURL := os.Getenv("AMQP_SERVER_URL")
+	deviceRepo := repository.NewDeviceRepository(database)
+	if cfg.Push.Provider != "" {
+		logger.Info(fmt.Sprintf("Starting push channel with %s provider", cfg.Push.Provider))
+		pushChannel := initPushChannel(deviceRepo, cfg.Push)
+		pushCtx, stopPush := context.WithCancel(context.Background())
+		pushDone := make(chan struct{})
+		go func() {
+			pushChannel.Run(pushCtx)
+			close(pushDone)
+		}()
+		app.OnStop("push channel", func(ctx context.Context) error {
+			stopPush()
+			return lifecycle.WaitFor(pushDone)(ctx)
+		})
+		publishers = append(publishers, pushChannel)
+	}
+
+	notificationRepo := initNotificationRepo(database)
+	notificationService := initNotificationService(notificationRepo, publishers, emailer)
+	notificationHandler := initNotificationHandler(notificationService, notificationHub)
+
+	logger.Info("Starting outbox relay")
+	eventsClient := initEventsClient(cfg.Events)
+	publisher := initEventPublisher(eventsClient, cfg)
+	if closer, ok := publisher.(io.Closer); ok {
+		app.OnStop("system event publisher", func(ctx context.Context) error {
+			return closer.Close()
+		})
+	}
+	logger.Info(fmt.Sprintf("Publishing system events over %s", cfg.Events.Transport))
+	relay := initOutboxRelay(database, publisher, cfg)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	go func() {
+		relay.Run(relayCtx)
+		close(relayDone)
+	}()
+	app.OnStop("outbox relay", func(ctx context.Context) error {
+		stopRelay()
+		return lifecycle.WaitFor(relayDone)(ctx)
+	})
 
 	rabbit := rabbitmq.RMQConsumer{
-		ConnectionString:    amqpServerURL,
+		ConnectionString:    cfg.AMQP.URL,
+		Topology:            initTopology(cfg.AMQP),
 		NotificationService: notificationService,
+		RetryPolicy:         initRetryPolicy(cfg.AMQP),
+		Workers:             cfg.AMQP.Workers,
+		Prefetch:            cfg.AMQP.Prefetch,
+		BatchSize:           cfg.AMQP.BatchSize,
+		BatchWindow:         cfg.AMQP.BatchWindow(),
+		Logger:              utils.Logger(),
 	}
 
 	logger.Info("Starting RabbitMQ")
-	channel, _ := rabbit.StartRabbitMQ()
-
-	defer channel.Close()
-
-	messages, _ := channel.Consume(
-		"AddNotification-MS",          // queue name
-		"AddNotification-MS-consumer", // consumer
-		true,                          // auto-ack
-		false,                         // exclusive
-		false,                         // no local
-		false,                         // no wait
-		nil,                           // arguments
-	)
-
-	go rabbit.Worker(messages)
+	connectionManager := rabbitmq.NewConnectionManager(rabbit, utils.Logger())
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
+	consumerDone := make(chan struct{})
+	go func() {
+		connectionManager.Run(consumerCtx)
+		close(consumerDone)
+	}()
+	app.OnStop("RabbitMQ consumer", func(ctx context.Context) error {
+		stopConsumer()
+		return lifecycle.WaitFor(consumerDone)(ctx)
+	})
 
-	router := gin.Default()
+	router := gin.New()
+	router.Use(handler.RequestLogger(), gin.Recovery())
 
 	setupPrometherus()
 
@@ -231,8 +469,29 @@ func main() {
 
 	router.GET("/api/metrics", prometheusGin())
 
-	handleNotificationFunc(notificationHandler, router)
+	handleNotificationFunc(notificationHandler, verifier, router)
+	handleDeviceFunc(initDeviceHandler(deviceRepo), verifier, router)
+	handleHealthFunc(initHealthHandler(database, connectionManager, cfg.Events), router)
 
-	logger.Info(fmt.Sprintf("Starting server on port %s", os.Getenv("SERVER_PORT")))
-	http.ListenAndServe(port, cors.AllowAll().Handler(router))
+	server := &http.Server{
+		Addr:    cfg.Server.Addr(),
+		Handler: cors.AllowAll().Handler(router),
+	}
+	server.RegisterOnShutdown(notificationHub.Close)
+	app.OnStop("HTTP server", server.Shutdown)
+
+	go func() {
+		logger.Info(fmt.Sprintf("Starting server on port %s", cfg.Server.Port))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(err.Error())
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("Shutting down")
+
+	if err := app.Shutdown(cfg.Server.ShutdownTimeout()); err != nil {
+		os.Exit(1)
+	}
 }
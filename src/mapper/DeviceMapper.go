@@ -0,0 +1,17 @@
+package mapper
+
+import (
+	"notifications-ms/src/dto"
+	"notifications-ms/src/model"
+)
+
+func DeviceToDeviceDTO(device *model.Device) *dto.DeviceDTO {
+	var deviceDto dto.DeviceDTO
+
+	deviceDto.ID = device.ID
+	deviceDto.Token = device.Token
+	deviceDto.Platform = device.Platform
+	deviceDto.CreatedAt = device.CreatedAt
+
+	return &deviceDto
+}
@@ -11,6 +11,10 @@ func NotificationDTOToNotification(notificationDto *dto.NotificationDTO) *model.
 	notification.Message = notificationDto.Message
 	notification.UserAuth0ID = notificationDto.UserAuth0ID
 	notification.NotificationType = notificationDto.NotificationType
+	if notificationDto.IdempotencyKey != "" {
+		idempotencyKey := notificationDto.IdempotencyKey
+		notification.IdempotencyKey = &idempotencyKey
+	}
 
 	return &notification
 }
@@ -18,9 +22,16 @@ func NotificationDTOToNotification(notificationDto *dto.NotificationDTO) *model.
 func NotificationToNotificationDTO(notification *model.Notification) *dto.NotificationDTO {
 	var notificationDto dto.NotificationDTO
 
+	notificationDto.ID = notification.ID
 	notificationDto.Message = notification.Message
 	notificationDto.UserAuth0ID = notification.UserAuth0ID
 	notificationDto.NotificationType = notification.NotificationType
+	notificationDto.Read = notification.Read
+	notificationDto.ReadAt = notification.ReadAt
+	notificationDto.CreatedAt = notification.CreatedAt
+	if notification.IdempotencyKey != nil {
+		notificationDto.IdempotencyKey = *notification.IdempotencyKey
+	}
 
 	return &notificationDto
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"notifications-ms/src/config"
+	"notifications-ms/src/migrations"
+	"notifications-ms/src/utils"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | version"
+
+// runMigrate implements the migrate subcommand. It only needs the database
+// settings, so it can run as a deploy step before the service starts.
+func runMigrate(configPath string, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		return err
+	}
+	utils.SetLoggerService(cfg.Database.Name)
+
+	database, err := initDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	migrator, err := migrations.NewMigrator(database.DB(), utils.Logger())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("steps must be a positive number, got %q", args[1])
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "version":
+	default:
+		return errors.New(migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema version %d (latest %d)\n", version, migrator.Latest())
+	return nil
+}
+
+// checkSchema refuses to start against a schema the code was not written for,
+// optionally bringing it up to date first.
+func checkSchema(database *gorm.DB, cfg config.DatabaseConfig) error {
+	migrator, err := migrations.NewMigrator(database.DB(), utils.Logger())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if cfg.MigrateOnStartup {
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	}
+
+	return migrator.Check(ctx)
+}
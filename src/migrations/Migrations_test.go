@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationsUnitTestsSuite struct {
+	suite.Suite
+}
+
+func TestMigrationsUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(MigrationsUnitTestsSuite))
+}
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func (suite *MigrationsUnitTestsSuite) TestLoad_OrdersByVersion() {
+	migrations, err := Load(fstest.MapFS{
+		"0002_second.up.sql":   file("CREATE INDEX"),
+		"0002_second.down.sql": file("DROP INDEX"),
+		"0001_first.up.sql":    file("CREATE TABLE"),
+		"0001_first.down.sql":  file("DROP TABLE"),
+	})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []Migration{
+		{Version: 1, Name: "first", Up: "CREATE TABLE", Down: "DROP TABLE"},
+		{Version: 2, Name: "second", Up: "CREATE INDEX", Down: "DROP INDEX"},
+	}, migrations)
+}
+
+func (suite *MigrationsUnitTestsSuite) TestLoad_MissingDown() {
+	_, err := Load(fstest.MapFS{
+		"0001_first.up.sql": file("CREATE TABLE"),
+	})
+
+	assert.EqualError(suite.T(), err, "migration 1_first needs both an up and a down file")
+}
+
+func (suite *MigrationsUnitTestsSuite) TestLoad_Gap() {
+	_, err := Load(fstest.MapFS{
+		"0001_first.up.sql":   file("CREATE TABLE"),
+		"0001_first.down.sql": file("DROP TABLE"),
+		"0003_third.up.sql":   file("CREATE INDEX"),
+		"0003_third.down.sql": file("DROP INDEX"),
+	})
+
+	assert.EqualError(suite.T(), err, "migration versions must be contiguous, expected 2 but found 3")
+}
+
+func (suite *MigrationsUnitTestsSuite) TestLoad_UnexpectedFile() {
+	_, err := Load(fstest.MapFS{
+		"first.sql": file("CREATE TABLE"),
+	})
+
+	assert.EqualError(suite.T(), err, "unexpected migration file first.sql")
+}
+
+func (suite *MigrationsUnitTestsSuite) TestEmbedded() {
+	migrations, err := Embedded()
+
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), migrations)
+
+	migrator := Migrator{Migrations: migrations}
+	assert.Equal(suite.T(), len(migrations), migrator.Latest())
+}
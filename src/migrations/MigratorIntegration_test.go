@@ -0,0 +1,97 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"notifications-ms/src/utils"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// The suite migrates its own schema so that reverting migrations does not
+// drop the table other integration suites are using at the same time.
+const integrationSchema = "migrations_integration_test"
+
+type MigratorIntegrationTestSuite struct {
+	suite.Suite
+	db       *sql.DB
+	migrator *Migrator
+}
+
+func connect(searchPath string) (*sql.DB, error) {
+	connectionString := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable search_path=%s",
+		os.Getenv("DATABASE_DOMAIN"),
+		os.Getenv("DATABASE_USERNAME"),
+		os.Getenv("DATABASE_PASSWORD"),
+		os.Getenv("DATABASE_SCHEMA"),
+		os.Getenv("DATABASE_PORT"),
+		searchPath,
+	)
+	return sql.Open("postgres", connectionString)
+}
+
+func (suite *MigratorIntegrationTestSuite) SetupSuite() {
+	db, err := connect(integrationSchema)
+	suite.Require().NoError(err)
+	suite.db = db
+
+	suite.migrator, err = NewMigrator(suite.db, utils.Logger())
+	suite.Require().NoError(err)
+}
+
+func (suite *MigratorIntegrationTestSuite) SetupTest() {
+	_, err := suite.db.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE; CREATE SCHEMA %s", integrationSchema, integrationSchema))
+	suite.Require().NoError(err)
+}
+
+func (suite *MigratorIntegrationTestSuite) TearDownSuite() {
+	suite.db.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", integrationSchema))
+	suite.db.Close()
+}
+
+func TestMigratorIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, new(MigratorIntegrationTestSuite))
+}
+
+func (suite *MigratorIntegrationTestSuite) TestIntegrationMigrator_UpDownUp() {
+	ctx := context.Background()
+
+	assert.ErrorIs(suite.T(), suite.migrator.Check(ctx), ErrSchemaOutdated)
+
+	assert.NoError(suite.T(), suite.migrator.Up(ctx))
+	assert.NoError(suite.T(), suite.migrator.Check(ctx))
+
+	_, err := suite.db.Exec("INSERT INTO notifications (message, user_auth0_id, notification_type, idempotency_key) VALUES ('Hi', 'auth0|1', 1, 'key-1')")
+	assert.NoError(suite.T(), err)
+	_, err = suite.db.Exec("INSERT INTO notifications (message, user_auth0_id, notification_type, idempotency_key) VALUES ('Hi', 'auth0|1', 1, 'key-1')")
+	assert.Error(suite.T(), err)
+
+	assert.NoError(suite.T(), suite.migrator.Down(ctx, 1))
+	version, err := suite.migrator.Version(ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.migrator.Latest()-1, version)
+
+	assert.NoError(suite.T(), suite.migrator.Down(ctx, suite.migrator.Latest()))
+	version, _ = suite.migrator.Version(ctx)
+	assert.Equal(suite.T(), 0, version)
+
+	assert.NoError(suite.T(), suite.migrator.Up(ctx))
+	assert.NoError(suite.T(), suite.migrator.Check(ctx))
+}
+
+func (suite *MigratorIntegrationTestSuite) TestIntegrationMigrator_SchemaTooNew() {
+	ctx := context.Background()
+	assert.NoError(suite.T(), suite.migrator.Up(ctx))
+
+	_, err := suite.db.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, 'future')", suite.migrator.Latest()+1)
+	assert.NoError(suite.T(), err)
+	defer suite.db.Exec("DELETE FROM schema_migrations WHERE version = $1", suite.migrator.Latest()+1)
+
+	assert.ErrorIs(suite.T(), suite.migrator.Check(ctx), ErrSchemaTooNew)
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id serial PRIMARY KEY,
+    message text,
+    user_auth0_id text,
+    notification_type integer
+);
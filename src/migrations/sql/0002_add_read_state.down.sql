@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_notifications_user_read;
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS read_at;
+ALTER TABLE notifications DROP COLUMN IF EXISTS read;
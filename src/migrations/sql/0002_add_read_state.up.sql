@@ -0,0 +1,4 @@
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS read boolean NOT NULL DEFAULT false;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS read_at timestamp with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_read ON notifications (user_auth0_id, read);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS uix_notifications_idempotency_key;
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS devices (
+    id serial PRIMARY KEY,
+    user_auth0_id text NOT NULL,
+    token text NOT NULL,
+    platform text NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_devices_user_auth0_id ON devices (user_auth0_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uix_devices_token ON devices (token);
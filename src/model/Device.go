@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/go-playground/validator"
+)
+
+type DevicePlatform string
+
+const (
+	FCM  DevicePlatform = "fcm"
+	APNs DevicePlatform = "apns"
+)
+
+// Device is a push token registered by one of a user's apps. A token
+// belongs to at most one user; registering it again moves it.
+type Device struct {
+	ID          int            `json:"id"`
+	UserAuth0ID string         `json:"userAuth0ID" validate:"required"`
+	Token       string         `json:"token" validate:"required,max=4096"`
+	Platform    DevicePlatform `json:"platform" validate:"required,oneof=fcm apns"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+func (d *Device) Validate() error {
+	validate := validator.New()
+	return validate.Struct(d)
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"io"
+	"time"
 
 	"github.com/go-playground/validator"
 )
@@ -21,6 +22,10 @@ type Notification struct {
 	Message          string            `json:"message" validate:"required"`
 	UserAuth0ID      string            `json:"userAuth0ID" validate:"required"`
 	NotificationType *NotificationType `json:"notificationType" validate:"required"`
+	Read             bool              `json:"read"`
+	ReadAt           *time.Time        `json:"readAt"`
+	CreatedAt        time.Time         `json:"createdAt" gorm:"not null;default:CURRENT_TIMESTAMP"`
+	IdempotencyKey   *string           `json:"idempotencyKey,omitempty"`
 }
 
 func (n *Notification) Validate() error {
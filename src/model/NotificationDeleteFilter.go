@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// NotificationDeleteFilter narrows a bulk delete. Criteria are combined, and
+// an empty filter matches every notification of the user.
+type NotificationDeleteFilter struct {
+	IDs       []int
+	Types     []NotificationType
+	OlderThan *time.Time
+}
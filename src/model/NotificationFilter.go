@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+type NotificationFilter struct {
+	Since *time.Time
+	Until *time.Time
+	Types []NotificationType
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// OutboxEvent is a system event waiting to be delivered to events-ms. It is
+// written in the same transaction as the change it describes and removed
+// once delivered.
+type OutboxEvent struct {
+	ID            int
+	Message       string
+	OccurredAt    time.Time
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+}
+
+func NewOutboxEvent(message string) *OutboxEvent {
+	return &OutboxEvent{Message: message, OccurredAt: time.Now()}
+}
@@ -0,0 +1,172 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/events"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultPollInterval    = time.Second
+	DefaultBatchSize       = 20
+	DefaultDeliveryTimeout = 5 * time.Second
+	DefaultInitialBackoff  = time.Second
+	DefaultMaxBackoff      = 5 * time.Minute
+)
+
+var (
+	eventsDelivered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_events_delivered_total",
+		Help: "Number of system events delivered to events-ms.",
+	})
+	deliveryFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_delivery_failures_total",
+		Help: "Number of failed system event deliveries.",
+	})
+	pendingEvents = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "outbox_pending_events",
+		Help: "Number of system events waiting in the outbox.",
+	})
+	deliveryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "outbox_delivery_duration_seconds",
+		Help: "Duration of system event deliveries.",
+	})
+)
+
+// Relay delivers events from the outbox. Delivery is at least once: an event
+// whose delivery succeeded but could not be removed afterwards is sent again.
+type Relay struct {
+	Outbox          repository.IOutboxRepository
+	Publisher       events.Publisher
+	PollInterval    time.Duration
+	BatchSize       int
+	DeliveryTimeout time.Duration
+	InitialBackoff  time.Duration
+	MaxBackoff      time.Duration
+	Logger          *logrus.Entry
+}
+
+func NewRelay(outbox repository.IOutboxRepository, publisher events.Publisher, logger *logrus.Entry) *Relay {
+	return &Relay{
+		Outbox:          outbox,
+		Publisher:       publisher,
+		PollInterval:    DefaultPollInterval,
+		BatchSize:       DefaultBatchSize,
+		DeliveryTimeout: DefaultDeliveryTimeout,
+		InitialBackoff:  DefaultInitialBackoff,
+		MaxBackoff:      DefaultMaxBackoff,
+		Logger:          logger,
+	}
+}
+
+// Run polls the outbox until ctx is done.
+func (relay *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while batches come back full, there is more waiting.
+		for {
+			claimed, err := relay.RelayPending(ctx)
+			if errors.Is(err, events.ErrCircuitOpen) {
+				relay.Logger.Debug("Relaying outbox events paused, events-ms circuit breaker is open")
+			} else if err != nil {
+				relay.Logger.Error(fmt.Sprintf("Relaying outbox events failed: %v", err))
+			}
+			if err != nil || claimed < relay.BatchSize {
+				break
+			}
+		}
+
+		if count, err := relay.Outbox.CountEvents(); err == nil {
+			pendingEvents.Set(float64(count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending delivers one batch of due events and returns how many were
+// claimed. It gives up on the rest of the batch with events.ErrCircuitOpen
+// when the events-ms circuit breaker opens.
+func (relay *Relay) RelayPending(ctx context.Context) (int, error) {
+	claimed, err := relay.Outbox.ClaimEvents(relay.BatchSize, relay.lease())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range claimed {
+		if ctx.Err() != nil {
+			// Unattempted events become due again when their lease expires.
+			return len(claimed), nil
+		}
+		if err := relay.deliver(ctx, event); err != nil {
+			return len(claimed), err
+		}
+	}
+
+	return len(claimed), nil
+}
+
+func (relay *Relay) deliver(ctx context.Context, event *model.OutboxEvent) error {
+	deliveryCtx, cancel := context.WithTimeout(ctx, relay.DeliveryTimeout)
+	defer cancel()
+
+	timer := prometheus.NewTimer(deliveryDuration)
+	err := relay.Publisher.Publish(deliveryCtx, &dto.EventRequestDTO{
+		Timestamp: event.OccurredAt.Format(events.TimestampLayout),
+		Message:   event.Message,
+	})
+	timer.ObserveDuration()
+
+	if errors.Is(err, events.ErrCircuitOpen) {
+		// Nothing was sent, so this is not an attempt; the event stays
+		// leased and becomes due again when the lease expires.
+		return err
+	}
+
+	if err != nil {
+		deliveryFailures.Inc()
+		backoff := relay.Backoff(event.Attempts + 1)
+		relay.Logger.Debug(fmt.Sprintf("Delivering outbox event %d failed, retrying in %s: %v", event.ID, backoff, err))
+		if err := relay.Outbox.MarkEventFailed(event.ID, backoff, err.Error()); err != nil {
+			relay.Logger.Error(err.Error())
+		}
+		return nil
+	}
+
+	eventsDelivered.Inc()
+	if err := relay.Outbox.DeleteEvent(event.ID); err != nil {
+		relay.Logger.Error(err.Error())
+	}
+	return nil
+}
+
+// Backoff returns the delay before the given retry, starting at 1.
+func (relay *Relay) Backoff(attempt int) time.Duration {
+	backoff := float64(relay.InitialBackoff) * math.Pow(2, float64(attempt-1))
+	if backoff > float64(relay.MaxBackoff) {
+		return relay.MaxBackoff
+	}
+	return time.Duration(backoff)
+}
+
+// The lease has to outlast delivering a whole batch, otherwise another relay
+// could claim the tail of the batch and send it twice.
+func (relay *Relay) lease() time.Duration {
+	return time.Duration(relay.BatchSize+1) * relay.DeliveryTimeout
+}
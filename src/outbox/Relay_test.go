@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/events"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"notifications-ms/src/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type publisherStub struct {
+	mu        sync.Mutex
+	published []*dto.EventRequestDTO
+	err       error
+}
+
+func (p *publisherStub) Publish(ctx context.Context, event *dto.EventRequestDTO) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+type RelayUnitTestsSuite struct {
+	suite.Suite
+	outbox    *repository.OutboxRepositoryMock
+	publisher *publisherStub
+	relay     *Relay
+}
+
+func TestRelayUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(RelayUnitTestsSuite))
+}
+
+func (suite *RelayUnitTestsSuite) SetupTest() {
+	suite.outbox = new(repository.OutboxRepositoryMock)
+	suite.publisher = &publisherStub{}
+	suite.relay = NewRelay(suite.outbox, suite.publisher, utils.Logger())
+}
+
+func event(id int, attempts int) *model.OutboxEvent {
+	occurredAt := time.Date(2022, 6, 1, 12, 30, 0, 0, time.UTC)
+	return &model.OutboxEvent{ID: id, Message: "Notifications deleted for user auth0|1", OccurredAt: occurredAt, Attempts: attempts}
+}
+
+func (suite *RelayUnitTestsSuite) TestRelayPending_DeliveredEventsAreRemoved() {
+	suite.outbox.On("ClaimEvents", DefaultBatchSize, mock.Anything).Return([]*model.OutboxEvent{event(1, 0), event(2, 0)}, nil).Once()
+	suite.outbox.On("DeleteEvent", 1).Return(nil).Once()
+	suite.outbox.On("DeleteEvent", 2).Return(nil).Once()
+
+	claimed, err := suite.relay.RelayPending(context.Background())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, claimed)
+	assert.Equal(suite.T(), []*dto.EventRequestDTO{
+		{Timestamp: "2022-06-01 12:30:00", Message: "Notifications deleted for user auth0|1"},
+		{Timestamp: "2022-06-01 12:30:00", Message: "Notifications deleted for user auth0|1"},
+	}, suite.publisher.published)
+	suite.outbox.AssertExpectations(suite.T())
+}
+
+func (suite *RelayUnitTestsSuite) TestRelayPending_FailedEventsAreRescheduledWithBackoff() {
+	suite.publisher.err = errors.New("connection refused")
+	suite.outbox.On("ClaimEvents", DefaultBatchSize, mock.Anything).Return([]*model.OutboxEvent{event(3, 2)}, nil).Once()
+	suite.outbox.On("MarkEventFailed", 3, 4*DefaultInitialBackoff, "connection refused").Return(nil).Once()
+
+	_, err := suite.relay.RelayPending(context.Background())
+
+	assert.Nil(suite.T(), err)
+	suite.outbox.AssertExpectations(suite.T())
+	suite.outbox.AssertNotCalled(suite.T(), "DeleteEvent", mock.Anything)
+}
+
+func (suite *RelayUnitTestsSuite) TestRelayPending_CircuitOpenStopsBatch() {
+	suite.publisher.err = events.ErrCircuitOpen
+	suite.outbox.On("ClaimEvents", DefaultBatchSize, mock.Anything).Return([]*model.OutboxEvent{event(6, 0), event(7, 0)}, nil).Once()
+
+	claimed, err := suite.relay.RelayPending(context.Background())
+
+	assert.Equal(suite.T(), events.ErrCircuitOpen, err)
+	assert.Equal(suite.T(), 2, claimed)
+	suite.outbox.AssertNotCalled(suite.T(), "MarkEventFailed", mock.Anything, mock.Anything, mock.Anything)
+	suite.outbox.AssertNotCalled(suite.T(), "DeleteEvent", mock.Anything)
+}
+
+func (suite *RelayUnitTestsSuite) TestRelayPending_StopsWhenCancelled() {
+	suite.outbox.On("ClaimEvents", DefaultBatchSize, mock.Anything).Return([]*model.OutboxEvent{event(4, 0)}, nil).Once()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := suite.relay.RelayPending(ctx)
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), suite.publisher.published)
+	suite.outbox.AssertNotCalled(suite.T(), "MarkEventFailed", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *RelayUnitTestsSuite) TestRelayPending_ClaimError() {
+	suite.outbox.On("ClaimEvents", DefaultBatchSize, mock.Anything).Return(nil, errors.New("database is down")).Once()
+
+	_, err := suite.relay.RelayPending(context.Background())
+
+	assert.EqualError(suite.T(), err, "database is down")
+}
+
+func (suite *RelayUnitTestsSuite) TestRun_DeliversUntilCancelled() {
+	suite.relay.PollInterval = 10 * time.Millisecond
+	suite.outbox.On("ClaimEvents", DefaultBatchSize, mock.Anything).Return([]*model.OutboxEvent{event(5, 0)}, nil).Once()
+	suite.outbox.On("ClaimEvents", DefaultBatchSize, mock.Anything).Return([]*model.OutboxEvent{}, nil)
+	suite.outbox.On("DeleteEvent", 5).Return(nil).Once()
+	suite.outbox.On("CountEvents").Return(int64(0), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		suite.relay.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(suite.T(), func() bool {
+		suite.publisher.mu.Lock()
+		defer suite.publisher.mu.Unlock()
+		return len(suite.publisher.published) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		suite.Fail("relay did not stop")
+	}
+}
+
+func (suite *RelayUnitTestsSuite) TestBackoff() {
+	assert.Equal(suite.T(), time.Second, suite.relay.Backoff(1))
+	assert.Equal(suite.T(), 8*time.Second, suite.relay.Backoff(4))
+	assert.Equal(suite.T(), DefaultMaxBackoff, suite.relay.Backoff(20))
+}
@@ -0,0 +1,161 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultQueueSize   = 1000
+	DefaultWorkers     = 2
+	DefaultSendTimeout = 5 * time.Second
+)
+
+var (
+	pushesSent = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "push_notifications_sent_total",
+		Help: "Number of push notifications accepted by the provider.",
+	})
+	pushFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "push_notification_failures_total",
+		Help: "Number of push notifications the provider did not accept.",
+	})
+	pushesDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "push_notifications_dropped_total",
+		Help: "Number of notifications not pushed because the push queue was full.",
+	})
+	tokensPruned = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "push_tokens_pruned_total",
+		Help: "Number of device tokens removed after the provider reported them invalid.",
+	})
+)
+
+var titles = map[model.NotificationType]string{
+	model.Message: "New message",
+	model.Follow:  "New follower",
+	model.Like:    "New like",
+	model.Comment: "New comment",
+}
+
+const defaultTitle = "New notification"
+
+// Channel pushes new notifications to every device the user registered.
+// Publish only queues the notification; Run looks up the devices, sends
+// and unregisters the tokens the provider rejects as invalid.
+type Channel struct {
+	Devices     repository.IDeviceRepository
+	Provider    Provider
+	Workers     int
+	SendTimeout time.Duration
+	Logger      *logrus.Entry
+
+	queue chan *dto.NotificationDTO
+}
+
+func NewChannel(devices repository.IDeviceRepository, provider Provider, queueSize int, logger *logrus.Entry) *Channel {
+	return &Channel{
+		Devices:     devices,
+		Provider:    provider,
+		Workers:     DefaultWorkers,
+		SendTimeout: DefaultSendTimeout,
+		Logger:      logger,
+		queue:       make(chan *dto.NotificationDTO, queueSize),
+	}
+}
+
+func (channel *Channel) Publish(notification *dto.NotificationDTO) {
+	select {
+	case channel.queue <- notification:
+	default:
+		pushesDropped.Inc()
+		channel.Logger.Error(fmt.Sprintf("Push queue is full, dropping push for notification %d", notification.ID))
+	}
+}
+
+// Run pushes queued notifications until ctx is done. Notifications still
+// queued then are not pushed; they are already stored.
+func (channel *Channel) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < channel.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			channel.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (channel *Channel) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-channel.queue:
+			channel.deliver(ctx, notification)
+		}
+	}
+}
+
+func (channel *Channel) deliver(ctx context.Context, notification *dto.NotificationDTO) {
+	devices, err := channel.Devices.GetDevicesByUserAuth0ID(notification.UserAuth0ID)
+	if err != nil {
+		channel.Logger.Error(fmt.Sprintf("Getting devices of user %s failed: %v", notification.UserAuth0ID, err))
+		return
+	}
+
+	message := Render(notification)
+	var invalid []string
+	for _, device := range devices {
+		sendCtx, cancel := context.WithTimeout(ctx, channel.SendTimeout)
+		err := channel.Provider.Send(sendCtx, device, message)
+		cancel()
+
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			invalid = append(invalid, device.Token)
+		case err != nil:
+			pushFailures.Inc()
+			channel.Logger.Error(fmt.Sprintf("Pushing notification %d to device %d failed: %v", notification.ID, device.ID, err))
+		default:
+			pushesSent.Inc()
+		}
+	}
+
+	if len(invalid) == 0 {
+		return
+	}
+
+	pruned, err := channel.Devices.DeleteDevicesByToken(invalid)
+	if err != nil {
+		channel.Logger.Error(fmt.Sprintf("Removing invalid device tokens failed: %v", err))
+		return
+	}
+	tokensPruned.Add(float64(pruned))
+	channel.Logger.Info(fmt.Sprintf("Removed %d invalid device tokens of user %s", pruned, notification.UserAuth0ID))
+}
+
+// Render turns a notification into the push message shown on the device.
+func Render(notification *dto.NotificationDTO) *Message {
+	title := defaultTitle
+	data := map[string]string{"notificationId": strconv.Itoa(notification.ID)}
+	if notification.NotificationType != nil {
+		if typeTitle, ok := titles[*notification.NotificationType]; ok {
+			title = typeTitle
+		}
+		data["notificationType"] = strconv.Itoa(int(*notification.NotificationType))
+	}
+
+	return &Message{Title: title, Body: notification.Message, Data: data}
+}
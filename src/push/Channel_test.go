@@ -0,0 +1,113 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"notifications-ms/src/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ChannelUnitTestsSuite struct {
+	suite.Suite
+	devices  *repository.DeviceRepositoryMock
+	provider *FakeProvider
+	channel  *Channel
+}
+
+func TestChannelUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(ChannelUnitTestsSuite))
+}
+
+func (suite *ChannelUnitTestsSuite) SetupTest() {
+	suite.devices = new(repository.DeviceRepositoryMock)
+	suite.provider = NewFakeProvider(utils.Logger())
+	suite.channel = NewChannel(suite.devices, suite.provider, 2, utils.Logger())
+}
+
+func notification(userAuth0ID string) *dto.NotificationDTO {
+	ntype := model.Like
+	return &dto.NotificationDTO{ID: 9, Message: "Jane liked your post", UserAuth0ID: userAuth0ID, NotificationType: &ntype}
+}
+
+func (suite *ChannelUnitTestsSuite) TestDeliver_SendsToEveryDevice() {
+	suite.devices.On("GetDevicesByUserAuth0ID", "auth0|1").Return([]*model.Device{
+		{ID: 1, UserAuth0ID: "auth0|1", Token: "phone", Platform: model.FCM},
+		{ID: 2, UserAuth0ID: "auth0|1", Token: "tablet", Platform: model.APNs},
+	}, nil).Once()
+
+	suite.channel.deliver(context.Background(), notification("auth0|1"))
+
+	sent := suite.provider.Sent()
+	assert.Equal(suite.T(), 2, len(sent))
+	assert.Equal(suite.T(), "phone", sent[0].Device.Token)
+	assert.Equal(suite.T(), "tablet", sent[1].Device.Token)
+	assert.Equal(suite.T(), Message{
+		Title: "New like",
+		Body:  "Jane liked your post",
+		Data:  map[string]string{"notificationId": "9", "notificationType": "2"},
+	}, sent[0].Message)
+	suite.devices.AssertNotCalled(suite.T(), "DeleteDevicesByToken", mock.Anything)
+}
+
+func (suite *ChannelUnitTestsSuite) TestDeliver_PrunesInvalidTokens() {
+	suite.devices.On("GetDevicesByUserAuth0ID", "auth0|2").Return([]*model.Device{
+		{ID: 3, UserAuth0ID: "auth0|2", Token: "uninstalled", Platform: model.FCM},
+		{ID: 4, UserAuth0ID: "auth0|2", Token: "current", Platform: model.FCM},
+	}, nil).Once()
+	suite.devices.On("DeleteDevicesByToken", []string{"uninstalled"}).Return(int64(1), nil).Once()
+	suite.provider.InvalidateToken("uninstalled")
+
+	suite.channel.deliver(context.Background(), notification("auth0|2"))
+
+	assert.Equal(suite.T(), 1, len(suite.provider.Sent()))
+	suite.devices.AssertExpectations(suite.T())
+}
+
+func (suite *ChannelUnitTestsSuite) TestDeliver_DeviceLookupFails() {
+	suite.devices.On("GetDevicesByUserAuth0ID", "auth0|3").Return(nil, errors.New("database is down")).Once()
+
+	suite.channel.deliver(context.Background(), notification("auth0|3"))
+
+	assert.Empty(suite.T(), suite.provider.Sent())
+}
+
+func (suite *ChannelUnitTestsSuite) TestPublish_DropsWhenQueueIsFull() {
+	for i := 0; i < 3; i++ {
+		suite.channel.Publish(notification("auth0|4"))
+	}
+
+	assert.Equal(suite.T(), 2, len(suite.channel.queue))
+}
+
+func (suite *ChannelUnitTestsSuite) TestRun_DeliversQueuedNotifications() {
+	suite.devices.On("GetDevicesByUserAuth0ID", "auth0|5").Return([]*model.Device{
+		{ID: 5, UserAuth0ID: "auth0|5", Token: "phone", Platform: model.FCM},
+	}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		suite.channel.Run(ctx)
+		close(done)
+	}()
+
+	suite.channel.Publish(notification("auth0|5"))
+
+	assert.Eventually(suite.T(), func() bool { return len(suite.provider.Sent()) == 1 }, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
+
+func (suite *ChannelUnitTestsSuite) TestRender_UnknownType() {
+	message := Render(&dto.NotificationDTO{ID: 1, Message: "Hello"})
+
+	assert.Equal(suite.T(), "New notification", message.Title)
+	assert.Equal(suite.T(), map[string]string{"notificationId": "1"}, message.Data)
+}
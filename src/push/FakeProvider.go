@@ -0,0 +1,55 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"notifications-ms/src/model"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type SentMessage struct {
+	Device  model.Device
+	Message Message
+}
+
+// FakeProvider records messages instead of sending them, for tests and for
+// running the service without push credentials.
+type FakeProvider struct {
+	Logger *logrus.Entry
+
+	mu      sync.Mutex
+	sent    []SentMessage
+	invalid map[string]bool
+}
+
+func NewFakeProvider(logger *logrus.Entry) *FakeProvider {
+	return &FakeProvider{Logger: logger, invalid: map[string]bool{}}
+}
+
+// InvalidateToken makes later sends to token fail with ErrInvalidToken.
+func (provider *FakeProvider) InvalidateToken(token string) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.invalid[token] = true
+}
+
+func (provider *FakeProvider) Send(ctx context.Context, device *model.Device, message *Message) error {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.invalid[device.Token] {
+		return fmt.Errorf("%w: device %d", ErrInvalidToken, device.ID)
+	}
+
+	provider.sent = append(provider.sent, SentMessage{Device: *device, Message: *message})
+	provider.Logger.Info(fmt.Sprintf("Fake push to %s device %d of user %s: %s", device.Platform, device.ID, device.UserAuth0ID, message.Title))
+	return nil
+}
+
+func (provider *FakeProvider) Sent() []SentMessage {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	return append([]SentMessage(nil), provider.sent...)
+}
@@ -0,0 +1,23 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"notifications-ms/src/model"
+)
+
+// ErrInvalidToken is reported by providers for tokens that will never work
+// again, such as those of uninstalled apps. Such devices are unregistered.
+var ErrInvalidToken = errors.New("push token is not valid")
+
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Provider delivers a message to a single device through a push service
+// such as FCM or APNs.
+type Provider interface {
+	Send(ctx context.Context, device *model.Device, message *Message) error
+}
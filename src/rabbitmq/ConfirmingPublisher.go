@@ -0,0 +1,93 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const DefaultConfirmTimeout = 30 * time.Second
+
+var (
+	ErrPublishNotConfirmed = errors.New("broker did not confirm the message")
+	ErrPublishReturned     = errors.New("broker returned the message")
+)
+
+// confirmSession is the part of a confirm-mode channel the publisher uses.
+type confirmSession interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	NotifyReturn(c chan amqp.Return) chan amqp.Return
+	Close() error
+}
+
+// ConfirmingPublisher republishes deliveries to the retry and dead letter
+// exchanges. Publish only returns nil once the broker has routed and confirmed
+// the message, so the original delivery can be acked safely afterwards.
+type ConfirmingPublisher struct {
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	session  confirmSession
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+}
+
+// NewConfirmingPublisher puts channel in confirm mode. The channel should not
+// be shared with other publishers, or their confirms would be mixed up.
+func NewConfirmingPublisher(channel *amqp.Channel) (*ConfirmingPublisher, error) {
+	if err := channel.Confirm(false); err != nil {
+		return nil, err
+	}
+	return newConfirmingPublisher(channel), nil
+}
+
+func newConfirmingPublisher(session confirmSession) *ConfirmingPublisher {
+	return &ConfirmingPublisher{
+		Timeout:  DefaultConfirmTimeout,
+		session:  session,
+		confirms: session.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		returns:  session.NotifyReturn(make(chan amqp.Return, 1)),
+	}
+}
+
+// Publish sends one message at a time and waits for its confirm, which keeps
+// confirms trivially matched to the message they belong to. Republishing only
+// happens for failed deliveries, so serializing the workers here is cheap.
+func (publisher *ConfirmingPublisher) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if err := publisher.session.Publish(exchange, key, mandatory, immediate, msg); err != nil {
+		return err
+	}
+
+	timeout := time.NewTimer(publisher.Timeout)
+	defer timeout.Stop()
+
+	select {
+	case confirmation, ok := <-publisher.confirms:
+		if !ok {
+			return errors.New("broker channel closed before confirming the message")
+		}
+		if !confirmation.Ack {
+			return ErrPublishNotConfirmed
+		}
+	case <-timeout.C:
+		// A confirm arriving later would be taken for the next message's, so
+		// give up on the channel; the connection manager reconnects.
+		publisher.session.Close()
+		return ErrPublishNotConfirmed
+	}
+
+	// The broker sends basic.return before the ack of an unroutable message.
+	select {
+	case returned := <-publisher.returns:
+		return fmt.Errorf("%w: %s", ErrPublishReturned, returned.ReplyText)
+	default:
+		return nil
+	}
+}
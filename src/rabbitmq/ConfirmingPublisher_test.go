@@ -0,0 +1,98 @@
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// confirmSessionStub answers every publish the way the broker would: an
+// optional basic.return followed by the confirm.
+type confirmSessionStub struct {
+	ack       bool
+	returned  bool
+	silent    bool
+	closed    bool
+	mandatory bool
+	confirms  chan amqp.Confirmation
+	returns   chan amqp.Return
+	tag       uint64
+}
+
+func (session *confirmSessionStub) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	session.mandatory = mandatory
+	if session.silent {
+		return nil
+	}
+	session.tag++
+	if session.returned {
+		session.returns <- amqp.Return{ReplyText: "NO_ROUTE", Exchange: exchange, RoutingKey: key}
+	}
+	session.confirms <- amqp.Confirmation{DeliveryTag: session.tag, Ack: session.ack}
+	return nil
+}
+
+func (session *confirmSessionStub) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	session.confirms = confirm
+	return confirm
+}
+
+func (session *confirmSessionStub) NotifyReturn(c chan amqp.Return) chan amqp.Return {
+	session.returns = c
+	return c
+}
+
+func (session *confirmSessionStub) Close() error {
+	session.closed = true
+	return nil
+}
+
+type ConfirmingPublisherUnitTestsSuite struct {
+	suite.Suite
+}
+
+func TestConfirmingPublisherUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(ConfirmingPublisherUnitTestsSuite))
+}
+
+func (suite *ConfirmingPublisherUnitTestsSuite) TestPublish_AckedMessageSucceeds() {
+	session := &confirmSessionStub{ack: true}
+	publisher := newConfirmingPublisher(session)
+
+	err := publisher.Publish(DeadLetterExchange, DeadLetterRoutingKey, true, false, amqp.Publishing{})
+
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), session.mandatory)
+}
+
+func (suite *ConfirmingPublisherUnitTestsSuite) TestPublish_NackedMessageFails() {
+	publisher := newConfirmingPublisher(&confirmSessionStub{ack: false})
+
+	err := publisher.Publish(DeadLetterExchange, DeadLetterRoutingKey, true, false, amqp.Publishing{})
+
+	assert.True(suite.T(), errors.Is(err, ErrPublishNotConfirmed))
+}
+
+func (suite *ConfirmingPublisherUnitTestsSuite) TestPublish_ReturnedMessageFails() {
+	publisher := newConfirmingPublisher(&confirmSessionStub{ack: true, returned: true})
+
+	err := publisher.Publish(DeadLetterExchange, DeadLetterRoutingKey, true, false, amqp.Publishing{})
+
+	assert.True(suite.T(), errors.Is(err, ErrPublishReturned))
+	assert.Contains(suite.T(), err.Error(), "NO_ROUTE")
+}
+
+func (suite *ConfirmingPublisherUnitTestsSuite) TestPublish_MissingConfirmClosesSession() {
+	session := &confirmSessionStub{silent: true}
+	publisher := newConfirmingPublisher(session)
+	publisher.Timeout = 10 * time.Millisecond
+
+	err := publisher.Publish(DeadLetterExchange, DeadLetterRoutingKey, true, false, amqp.Publishing{})
+
+	assert.True(suite.T(), errors.Is(err, ErrPublishNotConfirmed))
+	assert.True(suite.T(), session.closed)
+}
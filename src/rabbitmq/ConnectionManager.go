@@ -0,0 +1,188 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+)
+
+const (
+	DefaultInitialReconnectBackoff = time.Second
+	DefaultMaxReconnectBackoff     = 30 * time.Second
+)
+
+type ConnectionState int32
+
+const (
+	Disconnected ConnectionState = iota
+	Connecting
+	Connected
+)
+
+func (state ConnectionState) String() string {
+	switch state {
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	default:
+		return "disconnected"
+	}
+}
+
+var connectionStateGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "rabbitmq_connection_state",
+	Help: "State of the RabbitMQ consumer connection (0 disconnected, 1 connecting, 2 connected).",
+})
+
+var reconnectsTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "rabbitmq_reconnects_total",
+	Help: "Total number of RabbitMQ connection attempts after the connection was lost or refused.",
+})
+
+// ConnectionManager keeps the consumer attached to the broker. It dials,
+// declares the topology and starts the worker, then watches the connection
+// and channel and starts over with exponential backoff when either closes.
+type ConnectionManager struct {
+	Consumer       RMQConsumer
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Logger         *logrus.Entry
+
+	state int32
+}
+
+func NewConnectionManager(consumer RMQConsumer, logger *logrus.Entry) *ConnectionManager {
+	return &ConnectionManager{
+		Consumer:       consumer,
+		InitialBackoff: DefaultInitialReconnectBackoff,
+		MaxBackoff:     DefaultMaxReconnectBackoff,
+		Logger:         logger,
+	}
+}
+
+func (manager *ConnectionManager) State() ConnectionState {
+	return ConnectionState(atomic.LoadInt32(&manager.state))
+}
+
+// Check reports whether the consumer is attached to the broker, for readiness
+// probes.
+func (manager *ConnectionManager) Check(ctx context.Context) error {
+	if state := manager.State(); state != Connected {
+		return fmt.Errorf("consumer is %s", state)
+	}
+	return nil
+}
+
+func (manager *ConnectionManager) setState(state ConnectionState) {
+	atomic.StoreInt32(&manager.state, int32(state))
+	connectionStateGauge.Set(float64(state))
+}
+
+// Run blocks until ctx is cancelled and in-flight deliveries are handled.
+func (manager *ConnectionManager) Run(ctx context.Context) {
+	backoff := manager.InitialBackoff
+
+	for ctx.Err() == nil {
+		manager.setState(Connecting)
+
+		err := manager.consume(ctx)
+		manager.setState(Disconnected)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			backoff = manager.InitialBackoff
+		} else {
+			manager.Logger.Error(err.Error())
+		}
+
+		manager.Logger.Info(fmt.Sprintf("Reconnecting to RabbitMQ in %s", backoff))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		reconnectsTotal.Inc()
+		backoff = nextBackoff(backoff, manager.MaxBackoff)
+	}
+}
+
+// consume returns nil when an established connection was lost and an error
+// when it could not be established in the first place.
+func (manager *ConnectionManager) consume(ctx context.Context) error {
+	connection, channel, err := manager.Consumer.StartRabbitMQ()
+	if err != nil {
+		return fmt.Errorf("failed to start RabbitMQ: %w", err)
+	}
+	defer connection.Close()
+
+	messages, err := manager.Consumer.Consume(channel)
+	if err != nil {
+		return fmt.Errorf("failed to consume from RabbitMQ: %w", err)
+	}
+
+	// Retries and dead letters are republished on a channel of their own, so
+	// its confirms are not mixed up with the consume channel's traffic.
+	publishChannel, err := connection.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open RabbitMQ publish channel: %w", err)
+	}
+
+	publisher, err := NewConfirmingPublisher(publishChannel)
+	if err != nil {
+		return fmt.Errorf("failed to enable RabbitMQ publisher confirms: %w", err)
+	}
+
+	connectionClosed := connection.NotifyClose(make(chan *amqp.Error, 1))
+	channelClosed := channel.NotifyClose(make(chan *amqp.Error, 1))
+	publishChannelClosed := publishChannel.NotifyClose(make(chan *amqp.Error, 1))
+
+	workerDone := make(chan struct{})
+	go func() {
+		manager.Consumer.Worker(publisher, messages)
+		close(workerDone)
+	}()
+
+	manager.setState(Connected)
+	manager.Logger.Info("Connected to RabbitMQ")
+
+	select {
+	case <-ctx.Done():
+		// Stop the broker from sending more, then let the worker handle and
+		// ack what it already received before the channel goes away.
+		manager.Logger.Info("Stopping RabbitMQ consumer")
+		channel.Cancel(manager.Consumer.topology().Consumer, false)
+		<-workerDone
+	case closeErr := <-connectionClosed:
+		manager.Logger.Error(fmt.Sprintf("RabbitMQ connection closed: %v", closeErr))
+	case closeErr := <-channelClosed:
+		manager.Logger.Error(fmt.Sprintf("RabbitMQ channel closed: %v", closeErr))
+	case closeErr := <-publishChannelClosed:
+		manager.Logger.Error(fmt.Sprintf("RabbitMQ publish channel closed: %v", closeErr))
+	}
+
+	publishChannel.Close()
+	channel.Close()
+	connection.Close()
+	<-workerDone
+
+	return nil
+}
+
+func nextBackoff(current time.Duration, max time.Duration) time.Duration {
+	next := current * 2
+	if next > max {
+		return max
+	}
+	return next
+}
@@ -0,0 +1,61 @@
+package rabbitmq
+
+import (
+	"context"
+	"net"
+	"notifications-ms/src/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConnectionManagerUnitTestsSuite struct {
+	suite.Suite
+}
+
+func TestConnectionManagerUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(ConnectionManagerUnitTestsSuite))
+}
+
+func unreachableBrokerURL() string {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().String()
+	listener.Close()
+	return "amqp://guest:guest@" + address + "/"
+}
+
+func (suite *ConnectionManagerUnitTestsSuite) TestConnectionManager_Run_KeepsRetryingUntilCancelled() {
+	manager := NewConnectionManager(RMQConsumer{ConnectionString: unreachableBrokerURL(), Logger: utils.Logger()}, utils.Logger())
+	manager.InitialBackoff = 10 * time.Millisecond
+	manager.MaxBackoff = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.NotEqual(suite.T(), Connected, manager.State())
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		suite.T().Fatal("Run did not return after cancellation")
+	}
+	assert.Equal(suite.T(), Disconnected, manager.State())
+}
+
+func (suite *ConnectionManagerUnitTestsSuite) TestNextBackoff_DoublesUpToMax() {
+	assert.Equal(suite.T(), 2*time.Second, nextBackoff(time.Second, 30*time.Second))
+	assert.Equal(suite.T(), 30*time.Second, nextBackoff(20*time.Second, 30*time.Second))
+}
+
+func (suite *ConnectionManagerUnitTestsSuite) TestConnectionState_String() {
+	assert.Equal(suite.T(), "connected", Connected.String())
+	assert.Equal(suite.T(), "disconnected", Disconnected.String())
+}
@@ -2,31 +2,87 @@ package rabbitmq
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"math"
 	"notifications-ms/src/dto"
-	"notifications-ms/src/handler"
 	"notifications-ms/src/service"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
 )
 
+const (
+	AddNotificationExchange   = "AddNotification-MS-exchange"
+	AddNotificationQueue      = "AddNotification-MS"
+	AddNotificationRoutingKey = "AddNotification-MS-routing-key"
+	AddNotificationConsumer   = "AddNotification-MS-consumer"
+
+	DeadLetterExchange   = "AddNotification-MS-dead-letter-exchange"
+	DeadLetterQueue      = "AddNotification-MS-dead-letter"
+	DeadLetterRoutingKey = "AddNotification-MS-dead-letter-routing-key"
+
+	FailureReasonHeader = "x-failure-reason"
+	FailedAtHeader      = "x-failed-at"
+
+	DefaultBatchWindow = 100 * time.Millisecond
+)
+
+var ErrUndecodableMessage = errors.New("undecodable message")
+
+type Publisher interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
 type RMQConsumer struct {
 	ConnectionString    string
+	Topology            Topology
 	NotificationService service.INotificationService
+	RetryPolicy         RetryPolicy
+	Workers             int
+	Prefetch            int
+	BatchSize           int
+	BatchWindow         time.Duration
+	Logger              *logrus.Entry
 }
 
-func (r RMQConsumer) StartRabbitMQ() (*amqp.Channel, error) {
-	connectRabbitMQ, errr := amqp.Dial(r.ConnectionString)
+func (r RMQConsumer) StartRabbitMQ() (*amqp.Connection, *amqp.Channel, error) {
+	connectRabbitMQ, err := amqp.Dial(r.ConnectionString)
 
-	if errr != nil {
-		return nil, errr
+	if err != nil {
+		return nil, nil, err
 	}
 
-	channelRabbitMQ, _ := connectRabbitMQ.Channel()
+	channelRabbitMQ, err := connectRabbitMQ.Channel()
+
+	if err == nil {
+		err = r.declareTopology(channelRabbitMQ)
+	}
+
+	if err != nil {
+		connectRabbitMQ.Close()
+		return nil, nil, err
+	}
+
+	return connectRabbitMQ, channelRabbitMQ, nil
+}
+
+func (r RMQConsumer) topology() Topology {
+	if r.Topology == (Topology{}) {
+		return DefaultTopology()
+	}
+	return r.Topology
+}
+
+func (r RMQConsumer) declareTopology(channelRabbitMQ *amqp.Channel) error {
+	topology := r.topology()
 
 	err := channelRabbitMQ.ExchangeDeclare(
-		"AddNotification-MS-exchange",
+		topology.Exchange,
 		"direct",
 		true,
 		false,
@@ -36,11 +92,11 @@ func (r RMQConsumer) StartRabbitMQ() (*amqp.Channel, error) {
 	)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	queue, err := channelRabbitMQ.QueueDeclare(
-		"AddNotification-MS",
+		topology.Queue,
 		true,
 		false,
 		false,
@@ -49,45 +105,403 @@ func (r RMQConsumer) StartRabbitMQ() (*amqp.Channel, error) {
 	)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	err = channelRabbitMQ.QueueBind(
 		queue.Name,
-		"AddNotification-MS-routing-key",
-		"AddNotification-MS-exchange",
+		topology.RoutingKey,
+		topology.Exchange,
+		false,
+		nil,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	err = channelRabbitMQ.ExchangeDeclare(
+		topology.DeadLetterExchange,
+		"direct",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	deadLetterQueue, err := channelRabbitMQ.QueueDeclare(
+		topology.DeadLetterQueue,
+		true,
+		false,
+		false,
 		false,
 		nil,
 	)
 
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	err = channelRabbitMQ.QueueBind(
+		deadLetterQueue.Name,
+		topology.DeadLetterRoutingKey,
+		topology.DeadLetterExchange,
+		false,
+		nil,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	err = channelRabbitMQ.ExchangeDeclare(
+		topology.RetryExchange,
+		"direct",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	for retry := 1; retry < r.RetryPolicy.MaxAttempts; retry++ {
+		retryQueue, err := channelRabbitMQ.QueueDeclare(
+			topology.RetryQueue(retry),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-dead-letter-exchange":    topology.Exchange,
+				"x-dead-letter-routing-key": topology.RoutingKey,
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		err = channelRabbitMQ.QueueBind(
+			retryQueue.Name,
+			retryQueue.Name,
+			topology.RetryExchange,
+			false,
+			nil,
+		)
+
+		if err != nil {
+			return err
+		}
 	}
 
 	err = channelRabbitMQ.Qos(
-		1,     // prefetch count
-		0,     // prefetch size
-		false, // global
+		r.prefetch(), // prefetch count
+		0,            // prefetch size
+		false,        // global
 	)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return channelRabbitMQ, nil
+	return nil
 }
 
-func (r RMQConsumer) HandleAddNotification(message []byte) {
-	var notificationDto dto.NotificationDTO
+func (r RMQConsumer) Consume(channel *amqp.Channel) (<-chan amqp.Delivery, error) {
+	topology := r.topology()
 
-	json.Unmarshal([]byte(message), &notificationDto)
+	return channel.Consume(
+		topology.Queue,    // queue name
+		topology.Consumer, // consumer
+		false,             // auto-ack
+		false,             // exclusive
+		false,             // no local
+		false,             // no wait
+		nil,               // arguments
+	)
+}
+
+func (r RMQConsumer) HandleAddNotification(delivery amqp.Delivery) error {
+	notificationDto, err := decodeDelivery(delivery)
+	if err != nil {
+		return err
+	}
 
-	r.NotificationService.AddNotification(&notificationDto)
-	handler.AddSystemEvent(time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf("New notification added for user %s, type: %d", notificationDto.UserAuth0ID, notificationDto.NotificationType))
+	return r.NotificationService.AddNotification(notificationDto)
 }
 
-func (r RMQConsumer) Worker(messages <-chan amqp.Delivery) {
+// decodeDelivery falls back to the AMQP message id as idempotency key when the
+// producer did not put one in the body.
+func decodeDelivery(delivery amqp.Delivery) (*dto.AddNotificationRequestDTO, error) {
+	var notificationDto dto.AddNotificationRequestDTO
+
+	if err := json.Unmarshal(delivery.Body, &notificationDto); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUndecodableMessage, err.Error())
+	}
+
+	if notificationDto.IdempotencyKey == "" {
+		notificationDto.IdempotencyKey = delivery.MessageId
+	}
+
+	return &notificationDto, nil
+}
+
+// HandleBatch stores all decodable, valid deliveries of the batch in one
+// insert. Only the failing deliveries are dead-lettered; if the insert itself
+// fails every remaining delivery goes through the retry path.
+func (r RMQConsumer) HandleBatch(publisher Publisher, batch []amqp.Delivery) {
+	pending := make([]amqp.Delivery, 0, len(batch))
+	notificationDtos := make([]*dto.AddNotificationRequestDTO, 0, len(batch))
+
+	for _, delivery := range batch {
+		notificationDto, err := decodeDelivery(delivery)
+		if err != nil {
+			r.Logger.Debug(err.Error())
+			r.deadLetter(publisher, delivery, err)
+			continue
+		}
+		pending = append(pending, delivery)
+		notificationDtos = append(notificationDtos, notificationDto)
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	invalid, err := r.NotificationService.AddNotifications(notificationDtos)
+	if err != nil {
+		r.Logger.Debug(err.Error())
+	}
+
+	for i, delivery := range pending {
+		switch {
+		case invalid[i] != nil:
+			r.deadLetter(publisher, delivery, invalid[i])
+		case err != nil:
+			r.retry(publisher, delivery, err)
+		default:
+			delivery.Ack(false)
+		}
+	}
+}
+
+func (r RMQConsumer) HandleDelivery(publisher Publisher, delivery amqp.Delivery) {
+	err := r.HandleAddNotification(delivery)
+
+	switch {
+	case err == nil:
+		delivery.Ack(false)
+	case errors.Is(err, ErrUndecodableMessage) || errors.Is(err, service.ErrInvalidNotification):
+		r.Logger.Debug(err.Error())
+		r.deadLetter(publisher, delivery, err)
+	default:
+		r.Logger.Debug(err.Error())
+		r.retry(publisher, delivery, err)
+	}
+}
+
+// Worker spreads deliveries over a pool of goroutines. Deliveries for the same
+// user always go to the same goroutine, so they are stored in the order they
+// were received. It returns once messages is closed and the pool has drained.
+func (r RMQConsumer) Worker(publisher Publisher, messages <-chan amqp.Delivery) {
+	workers := make([]chan amqp.Delivery, r.workers())
+	var wg sync.WaitGroup
+
+	for i := range workers {
+		workers[i] = make(chan amqp.Delivery, r.prefetch())
+		wg.Add(1)
+		go func(deliveries <-chan amqp.Delivery) {
+			defer wg.Done()
+			if r.BatchSize > 1 {
+				r.batchWorker(publisher, deliveries)
+				return
+			}
+			for delivery := range deliveries {
+				r.HandleDelivery(publisher, delivery)
+			}
+		}(workers[i])
+	}
+
 	for delivery := range messages {
-		r.HandleAddNotification(delivery.Body)
+		workers[workerIndex(delivery.Body, len(workers))] <- delivery
+	}
+
+	for _, deliveries := range workers {
+		close(deliveries)
+	}
+	wg.Wait()
+}
+
+// batchWorker collects deliveries until BatchSize is reached or BatchWindow
+// has passed since the first delivery of the batch arrived.
+func (r RMQConsumer) batchWorker(publisher Publisher, deliveries <-chan amqp.Delivery) {
+	batch := make([]amqp.Delivery, 0, r.BatchSize)
+	window := time.NewTimer(r.batchWindow())
+	stopTimer(window)
+
+	flush := func() {
+		if len(batch) > 0 {
+			r.HandleBatch(publisher, batch)
+			batch = make([]amqp.Delivery, 0, r.BatchSize)
+		}
+	}
+
+	for {
+		select {
+		case delivery, ok := <-deliveries:
+			if !ok {
+				stopTimer(window)
+				flush()
+				return
+			}
+
+			batch = append(batch, delivery)
+			if len(batch) == 1 {
+				window.Reset(r.batchWindow())
+			}
+			if len(batch) >= r.BatchSize {
+				stopTimer(window)
+				flush()
+			}
+		case <-window.C:
+			flush()
+		}
+	}
+}
+
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
+
+func (r RMQConsumer) batchWindow() time.Duration {
+	if r.BatchWindow <= 0 {
+		return DefaultBatchWindow
+	}
+	return r.BatchWindow
+}
+
+func (r RMQConsumer) workers() int {
+	if r.Workers < 1 {
+		return 1
+	}
+	return r.Workers
+}
+
+// Without an explicit prefetch every worker may hold a full batch unacked.
+func (r RMQConsumer) prefetch() int {
+	if r.Prefetch >= 1 {
+		return r.Prefetch
+	}
+	if r.BatchSize > 1 {
+		return r.workers() * r.BatchSize
+	}
+	return r.workers()
+}
+
+func workerIndex(body []byte, workers int) int {
+	var recipient struct {
+		UserAuth0ID string
+	}
+	json.Unmarshal(body, &recipient)
+
+	hash := fnv.New32a()
+	hash.Write([]byte(recipient.UserAuth0ID))
+	return int(hash.Sum32() % uint32(workers))
+}
+
+// retry republishes delivery to the retry queue for its attempt and, like
+// deadLetter, only acks it once the broker confirmed the copy.
+func (r RMQConsumer) retry(publisher Publisher, delivery amqp.Delivery, reason error) {
+	attempts := retryAttempt(delivery) + 1
+	if attempts >= r.RetryPolicy.MaxAttempts {
+		r.deadLetter(publisher, delivery, fmt.Errorf("giving up after %d attempts: %w", attempts, reason))
+		return
+	}
+
+	headers := copyHeaders(delivery.Headers)
+	headers[RetryAttemptHeader] = int32(attempts)
+	headers[FailureReasonHeader] = reason.Error()
+
+	delay := r.RetryPolicy.Delay(attempts)
+	r.Logger.Info(fmt.Sprintf("Retrying delivery in %s, attempt %d of %d", delay, attempts+1, r.RetryPolicy.MaxAttempts))
+
+	topology := r.topology()
+	err := publisher.Publish(
+		topology.RetryExchange,
+		topology.RetryQueue(attempts),
+		true,
+		false,
+		amqp.Publishing{
+			Headers:      headers,
+			ContentType:  delivery.ContentType,
+			MessageId:    delivery.MessageId,
+			Timestamp:    delivery.Timestamp,
+			DeliveryMode: amqp.Persistent,
+			Expiration:   strconv.FormatInt(int64(math.Ceil(float64(delay)/float64(time.Millisecond))), 10),
+			Body:         delivery.Body,
+		},
+	)
+
+	if err != nil {
+		r.Logger.Debug(err.Error())
+		delivery.Nack(false, true)
+		return
+	}
+
+	delivery.Ack(false)
+}
+
+// deadLetter only acks delivery once the broker confirmed its copy on the dead
+// letter exchange; if the copy was nacked or could not be routed the delivery
+// is requeued instead of being lost.
+func (r RMQConsumer) deadLetter(publisher Publisher, delivery amqp.Delivery, reason error) {
+	headers := copyHeaders(delivery.Headers)
+	headers[FailureReasonHeader] = reason.Error()
+	headers[FailedAtHeader] = time.Now().Format(time.RFC3339)
+
+	topology := r.topology()
+	err := publisher.Publish(
+		topology.DeadLetterExchange,
+		topology.DeadLetterRoutingKey,
+		true,
+		false,
+		amqp.Publishing{
+			Headers:      headers,
+			ContentType:  delivery.ContentType,
+			MessageId:    delivery.MessageId,
+			Timestamp:    delivery.Timestamp,
+			DeliveryMode: amqp.Persistent,
+			Body:         delivery.Body,
+		},
+	)
+
+	if err != nil {
+		r.Logger.Debug(err.Error())
+		delivery.Nack(false, true)
+		return
+	}
+
+	delivery.Ack(false)
+}
+
+func copyHeaders(source amqp.Table) amqp.Table {
+	headers := amqp.Table{}
+	for key, value := range source {
+		headers[key] = value
 	}
+	return headers
 }
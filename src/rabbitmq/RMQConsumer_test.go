@@ -0,0 +1,414 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/service"
+	"notifications-ms/src/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type notificationServiceStub struct {
+	service.INotificationService
+	mu      sync.Mutex
+	delay   time.Duration
+	err     error
+	added   []*dto.AddNotificationRequestDTO
+	batches int
+}
+
+func (stub *notificationServiceStub) AddNotification(notificationDto *dto.AddNotificationRequestDTO) error {
+	time.Sleep(stub.delay)
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	stub.added = append(stub.added, notificationDto)
+	return stub.err
+}
+
+func (stub *notificationServiceStub) AddNotifications(notificationDtos []*dto.AddNotificationRequestDTO) ([]error, error) {
+	time.Sleep(stub.delay)
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	stub.batches++
+
+	invalid := make([]error, len(notificationDtos))
+	for i, notificationDto := range notificationDtos {
+		if notificationDto.UserAuth0ID == "" {
+			invalid[i] = service.ErrInvalidNotification
+			continue
+		}
+		if stub.err == nil {
+			stub.added = append(stub.added, notificationDto)
+		}
+	}
+	return invalid, stub.err
+}
+
+type acknowledgerStub struct {
+	mu      sync.Mutex
+	acked   int
+	nacked  int
+	requeue bool
+}
+
+func (a *acknowledgerStub) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked++
+	return nil
+}
+
+func (a *acknowledgerStub) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacked++
+	a.requeue = requeue
+	return nil
+}
+
+func (a *acknowledgerStub) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+type publishedMessage struct {
+	exchange  string
+	key       string
+	mandatory bool
+	msg       amqp.Publishing
+}
+
+type publisherStub struct {
+	err       error
+	published []publishedMessage
+}
+
+func (p *publisherStub) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, publishedMessage{exchange, key, mandatory, msg})
+	return nil
+}
+
+type RMQConsumerUnitTestsSuite struct {
+	suite.Suite
+	service      *notificationServiceStub
+	publisher    *publisherStub
+	acknowledger *acknowledgerStub
+	consumer     RMQConsumer
+}
+
+func TestRMQConsumerUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(RMQConsumerUnitTestsSuite))
+}
+
+func (suite *RMQConsumerUnitTestsSuite) SetupTest() {
+	suite.service = &notificationServiceStub{}
+	suite.publisher = &publisherStub{}
+	suite.acknowledger = &acknowledgerStub{}
+	suite.consumer = RMQConsumer{NotificationService: suite.service, RetryPolicy: DefaultRetryPolicy(), Logger: utils.Logger()}
+}
+
+func (suite *RMQConsumerUnitTestsSuite) delivery(body string) amqp.Delivery {
+	return amqp.Delivery{Acknowledger: suite.acknowledger, Body: []byte(body)}
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_ValidMessageIsAcked() {
+	suite.consumer.HandleDelivery(suite.publisher, suite.delivery(`{"Message":"Hi","UserAuth0ID":"auth0|1","NotificationType":1}`))
+
+	assert.Equal(suite.T(), 1, len(suite.service.added))
+	assert.Equal(suite.T(), 1, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 0, len(suite.publisher.published))
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_UndecodableMessageIsDeadLettered() {
+	suite.consumer.HandleDelivery(suite.publisher, suite.delivery(`not json`))
+
+	assert.Equal(suite.T(), 0, len(suite.service.added))
+	assert.Equal(suite.T(), 1, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 1, len(suite.publisher.published))
+	assert.Equal(suite.T(), DeadLetterExchange, suite.publisher.published[0].exchange)
+	assert.True(suite.T(), suite.publisher.published[0].mandatory)
+	assert.Contains(suite.T(), suite.publisher.published[0].msg.Headers[FailureReasonHeader], ErrUndecodableMessage.Error())
+	assert.Equal(suite.T(), []byte(`not json`), suite.publisher.published[0].msg.Body)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_InvalidNotificationIsDeadLettered() {
+	suite.service.err = service.ErrInvalidNotification
+
+	suite.consumer.HandleDelivery(suite.publisher, suite.delivery(`{"Message":"Hi"}`))
+
+	assert.Equal(suite.T(), 1, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 1, len(suite.publisher.published))
+	assert.Equal(suite.T(), service.ErrInvalidNotification.Error(), suite.publisher.published[0].msg.Headers[FailureReasonHeader])
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_StorageFailureIsRetriedWithDelay() {
+	suite.service.err = errors.New("connection refused")
+
+	suite.consumer.HandleDelivery(suite.publisher, suite.delivery(`{"Message":"Hi","UserAuth0ID":"auth0|1","NotificationType":1}`))
+
+	assert.Equal(suite.T(), 1, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 1, len(suite.publisher.published))
+	published := suite.publisher.published[0]
+	assert.Equal(suite.T(), RetryExchange, published.exchange)
+	assert.Equal(suite.T(), DefaultTopology().RetryQueue(1), published.key)
+	assert.True(suite.T(), published.mandatory)
+	assert.Equal(suite.T(), "1000", published.msg.Expiration)
+	assert.Equal(suite.T(), int32(1), published.msg.Headers[RetryAttemptHeader])
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_RetryBacksOffExponentially() {
+	suite.service.err = errors.New("connection refused")
+	delivery := suite.delivery(`{"Message":"Hi","UserAuth0ID":"auth0|1","NotificationType":1}`)
+	delivery.Headers = amqp.Table{RetryAttemptHeader: int32(2)}
+
+	suite.consumer.HandleDelivery(suite.publisher, delivery)
+
+	published := suite.publisher.published[0]
+	assert.Equal(suite.T(), DefaultTopology().RetryQueue(3), published.key)
+	assert.Equal(suite.T(), "4000", published.msg.Expiration)
+	assert.Equal(suite.T(), int32(3), published.msg.Headers[RetryAttemptHeader])
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_ExhaustedRetriesAreDeadLettered() {
+	suite.service.err = errors.New("connection refused")
+	delivery := suite.delivery(`{"Message":"Hi","UserAuth0ID":"auth0|1","NotificationType":1}`)
+	delivery.Headers = amqp.Table{RetryAttemptHeader: int32(DefaultMaxAttempts - 1)}
+
+	suite.consumer.HandleDelivery(suite.publisher, delivery)
+
+	assert.Equal(suite.T(), 1, suite.acknowledger.acked)
+	published := suite.publisher.published[0]
+	assert.Equal(suite.T(), DeadLetterExchange, published.exchange)
+	assert.Contains(suite.T(), published.msg.Headers[FailureReasonHeader], "connection refused")
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_RetryPublishFailureIsRequeued() {
+	suite.service.err = errors.New("connection refused")
+	suite.publisher.err = errors.New("channel closed")
+
+	suite.consumer.HandleDelivery(suite.publisher, suite.delivery(`{"Message":"Hi","UserAuth0ID":"auth0|1","NotificationType":1}`))
+
+	assert.Equal(suite.T(), 0, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 1, suite.acknowledger.nacked)
+	assert.True(suite.T(), suite.acknowledger.requeue)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_NackedRetryIsRequeued() {
+	suite.service.err = errors.New("connection refused")
+	publisher := newConfirmingPublisher(&confirmSessionStub{ack: false})
+
+	suite.consumer.HandleDelivery(publisher, suite.delivery(`{"Message":"Hi","UserAuth0ID":"auth0|1","NotificationType":1}`))
+
+	assert.Equal(suite.T(), 0, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 1, suite.acknowledger.nacked)
+	assert.True(suite.T(), suite.acknowledger.requeue)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_ReturnedRetryIsRequeued() {
+	suite.service.err = errors.New("connection refused")
+	publisher := newConfirmingPublisher(&confirmSessionStub{ack: true, returned: true})
+
+	suite.consumer.HandleDelivery(publisher, suite.delivery(`{"Message":"Hi","UserAuth0ID":"auth0|1","NotificationType":1}`))
+
+	assert.Equal(suite.T(), 0, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 1, suite.acknowledger.nacked)
+	assert.True(suite.T(), suite.acknowledger.requeue)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestRetryPolicy_DelayIsCapped() {
+	policy := DefaultRetryPolicy()
+
+	assert.Equal(suite.T(), time.Second, policy.Delay(1))
+	assert.Equal(suite.T(), 8*time.Second, policy.Delay(4))
+	assert.Equal(suite.T(), DefaultMaxDelay, policy.Delay(20))
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_DeadLetterPublishFailureIsRequeued() {
+	suite.publisher.err = errors.New("channel closed")
+
+	suite.consumer.HandleDelivery(suite.publisher, suite.delivery(`not json`))
+
+	assert.Equal(suite.T(), 0, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 1, suite.acknowledger.nacked)
+	assert.True(suite.T(), suite.acknowledger.requeue)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_ReturnedDeadLetterIsRequeued() {
+	publisher := newConfirmingPublisher(&confirmSessionStub{ack: true, returned: true})
+
+	suite.consumer.HandleDelivery(publisher, suite.delivery(`not json`))
+
+	assert.Equal(suite.T(), 0, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 1, suite.acknowledger.nacked)
+	assert.True(suite.T(), suite.acknowledger.requeue)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestWorker_PreservesOrderPerUser() {
+	suite.service.delay = time.Millisecond
+	suite.consumer.Workers = 4
+	suite.consumer.Prefetch = 8
+
+	messages := make(chan amqp.Delivery, 40)
+	for i := 0; i < 10; i++ {
+		for _, user := range []string{"auth0|1", "auth0|2", "auth0|3", "auth0|4"} {
+			messages <- suite.delivery(fmt.Sprintf(`{"Message":"%d","UserAuth0ID":"%s","NotificationType":1}`, i, user))
+		}
+	}
+	close(messages)
+
+	suite.consumer.Worker(suite.publisher, messages)
+
+	assert.Equal(suite.T(), 40, suite.acknowledger.acked)
+	next := map[string]int{}
+	for _, notification := range suite.service.added {
+		assert.Equal(suite.T(), fmt.Sprint(next[notification.UserAuth0ID]), notification.Message)
+		next[notification.UserAuth0ID]++
+	}
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestWorkerIndex_SameUserSameWorker() {
+	first := workerIndex([]byte(`{"UserAuth0ID":"auth0|1","Message":"a"}`), 8)
+	second := workerIndex([]byte(`{"UserAuth0ID":"auth0|1","Message":"b"}`), 8)
+
+	assert.Equal(suite.T(), first, second)
+	assert.Less(suite.T(), first, 8)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleBatch_OnlyFailingDeliveriesAreDeadLettered() {
+	batch := []amqp.Delivery{
+		suite.delivery(`{"Message":"1","UserAuth0ID":"auth0|1","NotificationType":1}`),
+		suite.delivery(`not json`),
+		suite.delivery(`{"Message":"2","NotificationType":1}`),
+		suite.delivery(`{"Message":"3","UserAuth0ID":"auth0|2","NotificationType":1}`),
+	}
+
+	suite.consumer.HandleBatch(suite.publisher, batch)
+
+	assert.Equal(suite.T(), 1, suite.service.batches)
+	assert.Equal(suite.T(), 2, len(suite.service.added))
+	assert.Equal(suite.T(), 4, suite.acknowledger.acked)
+	assert.Equal(suite.T(), 2, len(suite.publisher.published))
+	for _, published := range suite.publisher.published {
+		assert.Equal(suite.T(), DeadLetterExchange, published.exchange)
+	}
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleBatch_StorageFailureRetriesEveryDelivery() {
+	suite.service.err = errors.New("connection refused")
+	batch := []amqp.Delivery{
+		suite.delivery(`{"Message":"1","UserAuth0ID":"auth0|1","NotificationType":1}`),
+		suite.delivery(`{"Message":"2","UserAuth0ID":"auth0|2","NotificationType":1}`),
+	}
+
+	suite.consumer.HandleBatch(suite.publisher, batch)
+
+	assert.Equal(suite.T(), 2, len(suite.publisher.published))
+	for _, published := range suite.publisher.published {
+		assert.Equal(suite.T(), RetryExchange, published.exchange)
+	}
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestWorker_BatchesUpToBatchSize() {
+	suite.consumer.BatchSize = 5
+	suite.consumer.BatchWindow = time.Hour
+
+	messages := make(chan amqp.Delivery, 10)
+	for i := 0; i < 10; i++ {
+		messages <- suite.delivery(fmt.Sprintf(`{"Message":"%d","UserAuth0ID":"auth0|1","NotificationType":1}`, i))
+	}
+	close(messages)
+
+	suite.consumer.Worker(suite.publisher, messages)
+
+	assert.Equal(suite.T(), 2, suite.service.batches)
+	assert.Equal(suite.T(), 10, suite.acknowledger.acked)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestWorker_FlushesPartialBatchAfterWindow() {
+	suite.consumer.BatchSize = 50
+	suite.consumer.BatchWindow = 10 * time.Millisecond
+
+	messages := make(chan amqp.Delivery)
+	done := make(chan struct{})
+	go func() {
+		suite.consumer.Worker(suite.publisher, messages)
+		close(done)
+	}()
+
+	messages <- suite.delivery(`{"Message":"1","UserAuth0ID":"auth0|1","NotificationType":1}`)
+
+	assert.Eventually(suite.T(), func() bool {
+		suite.acknowledger.mu.Lock()
+		defer suite.acknowledger.mu.Unlock()
+		return suite.acknowledger.acked == 1
+	}, time.Second, 5*time.Millisecond)
+
+	close(messages)
+	<-done
+}
+
+func benchmarkWorker(b *testing.B, batchSize int) {
+	stub := &notificationServiceStub{delay: 200 * time.Microsecond}
+	consumer := RMQConsumer{
+		NotificationService: stub,
+		RetryPolicy:         DefaultRetryPolicy(),
+		Workers:             4,
+		BatchSize:           batchSize,
+		BatchWindow:         10 * time.Millisecond,
+		Logger:              utils.Logger(),
+	}
+	acknowledger := &acknowledgerStub{}
+
+	bodies := make([][]byte, 64)
+	for i := range bodies {
+		bodies[i] = []byte(fmt.Sprintf(`{"Message":"Liked","UserAuth0ID":"auth0|%d","NotificationType":2}`, i))
+	}
+
+	b.ResetTimer()
+	messages := make(chan amqp.Delivery, 1024)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			messages <- amqp.Delivery{Acknowledger: acknowledger, Body: bodies[i%len(bodies)]}
+		}
+		close(messages)
+	}()
+	consumer.Worker(&publisherStub{}, messages)
+}
+
+// Both benchmarks simulate a 200µs database round trip per insert statement.
+func BenchmarkWorker_SingleInserts(b *testing.B) {
+	benchmarkWorker(b, 1)
+}
+
+func BenchmarkWorker_BatchedInserts(b *testing.B) {
+	benchmarkWorker(b, 50)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_MessageIdIsUsedAsIdempotencyKey() {
+	delivery := suite.delivery(`{"Message":"Hi","UserAuth0ID":"auth0|1","NotificationType":1}`)
+	delivery.MessageId = "message-1"
+
+	suite.consumer.HandleDelivery(suite.publisher, delivery)
+
+	assert.Equal(suite.T(), "message-1", suite.service.added[0].IdempotencyKey)
+}
+
+func (suite *RMQConsumerUnitTestsSuite) TestHandleDelivery_BodyIdempotencyKeyTakesPrecedence() {
+	delivery := suite.delivery(`{"Message":"Hi","UserAuth0ID":"auth0|1","NotificationType":1,"IdempotencyKey":"like-1"}`)
+	delivery.MessageId = "message-1"
+
+	suite.consumer.HandleDelivery(suite.publisher, delivery)
+
+	assert.Equal(suite.T(), "like-1", suite.service.added[0].IdempotencyKey)
+}
@@ -0,0 +1,60 @@
+package rabbitmq
+
+import (
+	"math"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	RetryExchange       = "AddNotification-MS-retry-exchange"
+	RetryAttemptHeader  = "x-retry-attempt"
+	DefaultMaxAttempts  = 5
+	DefaultInitialDelay = time.Second
+	DefaultMultiplier   = 2
+	DefaultMaxDelay     = time.Minute
+)
+
+// RetryPolicy describes how often and how late a failed delivery is
+// redelivered. MaxAttempts counts the first delivery, so a policy with
+// MaxAttempts of 1 dead-letters on the first failure.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	Multiplier   float64
+	MaxDelay     time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  DefaultMaxAttempts,
+		InitialDelay: DefaultInitialDelay,
+		Multiplier:   DefaultMultiplier,
+		MaxDelay:     DefaultMaxDelay,
+	}
+}
+
+// Delay returns the backoff before the given retry, starting at 1.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(retry-1))
+	if delay > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+func retryAttempt(delivery amqp.Delivery) int {
+	switch attempt := delivery.Headers[RetryAttemptHeader].(type) {
+	case int:
+		return attempt
+	case int16:
+		return int(attempt)
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	default:
+		return 0
+	}
+}
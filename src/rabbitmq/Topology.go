@@ -0,0 +1,34 @@
+package rabbitmq
+
+import "fmt"
+
+// Topology names the exchanges and queues the consumer declares and uses.
+type Topology struct {
+	Exchange             string
+	Queue                string
+	RoutingKey           string
+	Consumer             string
+	DeadLetterExchange   string
+	DeadLetterQueue      string
+	DeadLetterRoutingKey string
+	RetryExchange        string
+}
+
+func DefaultTopology() Topology {
+	return Topology{
+		Exchange:             AddNotificationExchange,
+		Queue:                AddNotificationQueue,
+		RoutingKey:           AddNotificationRoutingKey,
+		Consumer:             AddNotificationConsumer,
+		DeadLetterExchange:   DeadLetterExchange,
+		DeadLetterQueue:      DeadLetterQueue,
+		DeadLetterRoutingKey: DeadLetterRoutingKey,
+		RetryExchange:        RetryExchange,
+	}
+}
+
+// Each retry level gets its own queue so that all messages in a queue share
+// the same TTL and expire in order, back onto the main exchange.
+func (t Topology) RetryQueue(retry int) string {
+	return fmt.Sprintf("%s-retry-%d", t.Queue, retry)
+}
@@ -0,0 +1,112 @@
+package realtime
+
+import (
+	"notifications-ms/src/dto"
+	"sync"
+)
+
+const DefaultSubscriptionBufferSize = 64
+
+type Subscription struct {
+	UserAuth0ID string
+	C           chan *dto.NotificationDTO
+	closed      bool
+}
+
+// NotificationHub fans out newly stored notifications to the open streams of
+// their recipient. It only knows about subscribers of this instance.
+type NotificationHub struct {
+	BufferSize int
+
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewNotificationHub() *NotificationHub {
+	return &NotificationHub{
+		BufferSize:  DefaultSubscriptionBufferSize,
+		subscribers: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+func (hub *NotificationHub) Subscribe(userAuth0ID string) *Subscription {
+	subscription := &Subscription{
+		UserAuth0ID: userAuth0ID,
+		C:           make(chan *dto.NotificationDTO, hub.BufferSize),
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if hub.closed {
+		subscription.closed = true
+		close(subscription.C)
+		return subscription
+	}
+
+	if hub.subscribers[userAuth0ID] == nil {
+		hub.subscribers[userAuth0ID] = make(map[*Subscription]struct{})
+	}
+	hub.subscribers[userAuth0ID][subscription] = struct{}{}
+
+	return subscription
+}
+
+func (hub *NotificationHub) Unsubscribe(subscription *Subscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.remove(subscription)
+}
+
+// Publish never blocks: a subscriber whose buffer is full is dropped and its
+// channel closed, so the client reconnects and resumes from its last event.
+func (hub *NotificationHub) Publish(notification *dto.NotificationDTO) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for subscription := range hub.subscribers[notification.UserAuth0ID] {
+		select {
+		case subscription.C <- notification:
+		default:
+			hub.remove(subscription)
+		}
+	}
+}
+
+// Close ends every open stream so that the HTTP server can shut down; later
+// subscriptions are closed right away.
+func (hub *NotificationHub) Close() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.closed = true
+	for _, subscribers := range hub.subscribers {
+		for subscription := range subscribers {
+			hub.remove(subscription)
+		}
+	}
+}
+
+func (hub *NotificationHub) SubscriberCount(userAuth0ID string) int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	return len(hub.subscribers[userAuth0ID])
+}
+
+func (hub *NotificationHub) remove(subscription *Subscription) {
+	if subscription.closed {
+		return
+	}
+
+	subscription.closed = true
+	close(subscription.C)
+
+	subscribers := hub.subscribers[subscription.UserAuth0ID]
+	delete(subscribers, subscription)
+	if len(subscribers) == 0 {
+		delete(hub.subscribers, subscription.UserAuth0ID)
+	}
+}
@@ -0,0 +1,70 @@
+package realtime
+
+import (
+	"notifications-ms/src/dto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationHubUnitTestsSuite struct {
+	suite.Suite
+	hub *NotificationHub
+}
+
+func TestNotificationHubUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(NotificationHubUnitTestsSuite))
+}
+
+func (suite *NotificationHubUnitTestsSuite) SetupTest() {
+	suite.hub = NewNotificationHub()
+}
+
+func (suite *NotificationHubUnitTestsSuite) TestNotificationHub_Publish_DeliveredToEverySubscriberOfUser() {
+	first := suite.hub.Subscribe("auth0|1")
+	second := suite.hub.Subscribe("auth0|1")
+	other := suite.hub.Subscribe("auth0|2")
+
+	suite.hub.Publish(&dto.NotificationDTO{ID: 1, UserAuth0ID: "auth0|1"})
+
+	assert.Equal(suite.T(), 1, (<-first.C).ID)
+	assert.Equal(suite.T(), 1, (<-second.C).ID)
+	assert.Equal(suite.T(), 0, len(other.C))
+}
+
+func (suite *NotificationHubUnitTestsSuite) TestNotificationHub_Unsubscribe_ClosesChannel() {
+	subscription := suite.hub.Subscribe("auth0|1")
+
+	suite.hub.Unsubscribe(subscription)
+	suite.hub.Unsubscribe(subscription)
+
+	_, ok := <-subscription.C
+	assert.False(suite.T(), ok)
+	assert.Equal(suite.T(), 0, suite.hub.SubscriberCount("auth0|1"))
+}
+
+func (suite *NotificationHubUnitTestsSuite) TestNotificationHub_Publish_SlowSubscriberIsDropped() {
+	suite.hub.BufferSize = 1
+	subscription := suite.hub.Subscribe("auth0|1")
+
+	suite.hub.Publish(&dto.NotificationDTO{ID: 1, UserAuth0ID: "auth0|1"})
+	suite.hub.Publish(&dto.NotificationDTO{ID: 2, UserAuth0ID: "auth0|1"})
+
+	assert.Equal(suite.T(), 1, (<-subscription.C).ID)
+	_, ok := <-subscription.C
+	assert.False(suite.T(), ok)
+	assert.Equal(suite.T(), 0, suite.hub.SubscriberCount("auth0|1"))
+}
+
+func (suite *NotificationHubUnitTestsSuite) TestNotificationHub_Close_EndsAllSubscriptions() {
+	existing := suite.hub.Subscribe("auth0|1")
+
+	suite.hub.Close()
+	later := suite.hub.Subscribe("auth0|2")
+
+	_, existingOpen := <-existing.C
+	_, laterOpen := <-later.C
+	assert.False(suite.T(), existingOpen)
+	assert.False(suite.T(), laterOpen)
+}
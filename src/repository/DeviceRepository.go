@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"notifications-ms/src/model"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type IDeviceRepository interface {
+	RegisterDevice(*model.Device) (string, error)
+	UnregisterDevice(id int, userAuth0ID string) error
+	GetDevicesByUserAuth0ID(userAuth0ID string) ([]*model.Device, error)
+	DeleteDevicesByToken(tokens []string) (int64, error)
+}
+
+func NewDeviceRepository(database *gorm.DB) IDeviceRepository {
+	return &DeviceRepository{
+		Database: database,
+	}
+}
+
+type DeviceRepository struct {
+	Database *gorm.DB
+}
+
+// RegisterDevice stores the token, or hands it over to this user if another
+// account registered it before, as happens when users switch accounts on a
+// shared device. It returns the user the token belonged to before, or an
+// empty string for a new token.
+func (repo *DeviceRepository) RegisterDevice(device *model.Device) (string, error) {
+	now := time.Now()
+	device.UpdatedAt = now
+
+	row := repo.Database.Raw(
+		"WITH previous AS (SELECT user_auth0_id FROM devices WHERE token = ?) "+
+			"INSERT INTO devices (user_auth0_id, token, platform, created_at, updated_at) VALUES (?, ?, ?, ?, ?) "+
+			"ON CONFLICT (token) DO UPDATE SET user_auth0_id = EXCLUDED.user_auth0_id, platform = EXCLUDED.platform, updated_at = EXCLUDED.updated_at "+
+			"RETURNING id, created_at, COALESCE((SELECT user_auth0_id FROM previous), '')",
+		device.Token, device.UserAuth0ID, device.Token, device.Platform, now, now,
+	).Row()
+
+	var previousUserAuth0ID string
+	err := row.Scan(&device.ID, &device.CreatedAt, &previousUserAuth0ID)
+	return previousUserAuth0ID, err
+}
+
+func (repo *DeviceRepository) UnregisterDevice(id int, userAuth0ID string) error {
+	result := repo.Database.Where("id = ? AND user_auth0_id = ?", id, userAuth0ID).Delete(&model.Device{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (repo *DeviceRepository) GetDevicesByUserAuth0ID(userAuth0ID string) ([]*model.Device, error) {
+	var devices = []*model.Device{}
+	result := repo.Database.Where("user_auth0_id = ?", userAuth0ID).Order("id").Find(&devices)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return devices, nil
+}
+
+func (repo *DeviceRepository) DeleteDevicesByToken(tokens []string) (int64, error) {
+	if len(tokens) == 0 {
+		return 0, nil
+	}
+
+	result := repo.Database.Where("token IN (?)", tokens).Delete(&model.Device{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"notifications-ms/src/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type DeviceRepositoryMock struct {
+	mock.Mock
+}
+
+func (d *DeviceRepositoryMock) RegisterDevice(device *model.Device) (string, error) {
+	args := d.Called(device)
+	if args.Get(1) == nil {
+		return args.String(0), nil
+	}
+	return args.String(0), args.Get(1).(error)
+}
+
+func (d *DeviceRepositoryMock) UnregisterDevice(id int, userAuth0ID string) error {
+	args := d.Called(id, userAuth0ID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(error)
+}
+
+func (d *DeviceRepositoryMock) GetDevicesByUserAuth0ID(userAuth0ID string) ([]*model.Device, error) {
+	args := d.Called(userAuth0ID)
+	if args.Get(1) == nil {
+		return args.Get(0).([]*model.Device), nil
+	}
+	return nil, args.Get(1).(error)
+}
+
+func (d *DeviceRepositoryMock) DeleteDevicesByToken(tokens []string) (int64, error) {
+	args := d.Called(tokens)
+	if args.Get(1) == nil {
+		return args.Get(0).(int64), nil
+	}
+	return args.Get(0).(int64), args.Get(1).(error)
+}
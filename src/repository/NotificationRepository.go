@@ -1,32 +1,73 @@
 package repository
 
 import (
-	"fmt"
+	"database/sql"
+	"errors"
 	"notifications-ms/src/model"
+	"notifications-ms/src/utils"
 	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 )
 
+var ErrDuplicateNotification = errors.New("notification with this idempotency key already exists")
+
+const onIdempotencyConflict = "ON CONFLICT (idempotency_key) DO NOTHING"
+
 type INotificationRepository interface {
 	AddNotification(*model.Notification) error
+	AddNotifications([]*model.Notification) error
 	GetNotificationsByUserAuth0ID(userAuth0ID string) []*model.Notification
-	DeleteNotificationsByUserAuth0ID(userAuth0ID string)
+	GetNotificationsPageByUserAuth0ID(userAuth0ID string, filter *model.NotificationFilter, limit int, after *utils.Cursor) ([]*model.Notification, error)
+	GetNotificationsAfterID(userAuth0ID string, afterID int, limit int) ([]*model.Notification, error)
+	DeleteNotificationsByUserAuth0ID(userAuth0ID string, filter *model.NotificationDeleteFilter) (int64, error)
+	DeleteNotification(id int, userAuth0ID string) error
+	MarkNotificationAsRead(id int, userAuth0ID string) error
+	MarkNotificationsAsRead(ids []int, userAuth0ID string) (int64, error)
+	MarkAllNotificationsAsRead(userAuth0ID string) (int64, error)
+	CountUnreadNotifications(userAuth0ID string) (int64, error)
+	CountUnreadNotificationsByType(userAuth0ID string) (map[model.NotificationType]int64, error)
+	Transaction(fn func(notifications INotificationRepository, outbox IOutboxRepository) error) error
 }
 
 func NewNotificationRepository(database *gorm.DB) INotificationRepository {
 	return &NotificationRepository{
-		database,
+		Database: database,
 	}
 }
 
 type NotificationRepository struct {
-	Database *gorm.DB
+	Database      *gorm.DB
+	inTransaction bool
+}
+
+// Transaction runs fn with repositories bound to one database transaction, so
+// that a notification change and the outbox events describing it are stored
+// together or not at all.
+func (repo *NotificationRepository) Transaction(fn func(notifications INotificationRepository, outbox IOutboxRepository) error) error {
+	return repo.transaction(func(tx *gorm.DB) error {
+		return fn(&NotificationRepository{Database: tx, inTransaction: true}, &OutboxRepository{Database: tx})
+	})
+}
+
+// transaction joins the surrounding transaction if there is one, since gorm
+// cannot nest them.
+func (repo *NotificationRepository) transaction(fn func(tx *gorm.DB) error) error {
+	if repo.inTransaction {
+		return fn(repo.Database)
+	}
+	return repo.Database.Transaction(fn)
 }
 
 func (repo *NotificationRepository) AddNotification(notification *model.Notification) error {
-	result := repo.Database.Create(notification)
+	result := repo.Database.Set("gorm:insert_option", onIdempotencyConflict).Create(notification)
+
+	// Nothing is returned by the insert when the idempotency key is taken.
+	if errors.Is(result.Error, sql.ErrNoRows) {
+		return ErrDuplicateNotification
+	}
 
 	if result.Error != nil {
 		return result.Error
@@ -35,16 +76,216 @@ func (repo *NotificationRepository) AddNotification(notification *model.Notifica
 	return nil
 }
 
+// AddNotifications stores the whole batch with a single multi-row insert and
+// fills in the generated ids. Duplicates of already stored idempotency keys
+// are skipped and keep an id of 0.
+func (repo *NotificationRepository) AddNotifications(notifications []*model.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	placeholders := make([]string, len(notifications))
+	values := make([]interface{}, 0, len(notifications)*5)
+	for i, notification := range notifications {
+		if notification.CreatedAt.IsZero() {
+			notification.CreatedAt = now
+		}
+		placeholders[i] = "(?, ?, ?, ?, ?, ?)"
+		values = append(values, notification.Message, notification.UserAuth0ID, notification.NotificationType, notification.Read, notification.CreatedAt, notification.IdempotencyKey)
+	}
+
+	query := "INSERT INTO notifications (message, user_auth0_id, notification_type, read, created_at, idempotency_key) VALUES " +
+		strings.Join(placeholders, ", ") +
+		" " + onIdempotencyConflict + " RETURNING id, idempotency_key"
+
+	return repo.transaction(func(tx *gorm.DB) error {
+		rows, err := tx.Raw(query, values...).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		// Inserted rows come back in the order of the VALUES list, so skipped
+		// duplicates are found by walking both lists in step.
+		i := 0
+		for rows.Next() {
+			var id int
+			var idempotencyKey *string
+			if err := rows.Scan(&id, &idempotencyKey); err != nil {
+				return err
+			}
+
+			for i < len(notifications) && !sameIdempotencyKey(notifications[i].IdempotencyKey, idempotencyKey) {
+				i++
+			}
+			if i < len(notifications) {
+				notifications[i].ID = id
+				i++
+			}
+		}
+
+		return rows.Err()
+	})
+}
+
+func sameIdempotencyKey(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
 func (repo *NotificationRepository) GetNotificationsByUserAuth0ID(userAuth0ID string) []*model.Notification {
 	var notifications = []*model.Notification{}
-	if result := repo.Database.Find(&notifications, "user_auth0_id = ?", userAuth0ID); result.Error != nil {
+	if result := repo.Database.Order("created_at desc, id desc").Find(&notifications, "user_auth0_id = ?", userAuth0ID); result.Error != nil {
 		return nil
 	}
 
 	return notifications
 }
 
-func (repo *NotificationRepository) DeleteNotificationsByUserAuth0ID(userAuth0ID string) {
-	param := "'%" + strings.Split(userAuth0ID, "|")[1] + "'"
-	repo.Database.Exec(fmt.Sprintf("delete from notifications where user_auth0_id LIKE %s", param))
+func (repo *NotificationRepository) GetNotificationsPageByUserAuth0ID(userAuth0ID string, filter *model.NotificationFilter, limit int, after *utils.Cursor) ([]*model.Notification, error) {
+	var notifications = []*model.Notification{}
+
+	query := repo.Database.Where("user_auth0_id = ?", userAuth0ID)
+	if filter != nil {
+		if filter.Since != nil {
+			query = query.Where("created_at >= ?", *filter.Since)
+		}
+		if filter.Until != nil {
+			query = query.Where("created_at < ?", *filter.Until)
+		}
+		if len(filter.Types) > 0 {
+			query = query.Where("notification_type IN (?)", filter.Types)
+		}
+	}
+	if after != nil {
+		query = query.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+
+	if result := query.Order("created_at desc, id desc").Limit(limit).Find(&notifications); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return notifications, nil
+}
+
+func (repo *NotificationRepository) GetNotificationsAfterID(userAuth0ID string, afterID int, limit int) ([]*model.Notification, error) {
+	var notifications = []*model.Notification{}
+
+	result := repo.Database.Where("user_auth0_id = ? AND id > ?", userAuth0ID, afterID).
+		Order("id asc").
+		Limit(limit).
+		Find(&notifications)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return notifications, nil
+}
+
+func (repo *NotificationRepository) DeleteNotificationsByUserAuth0ID(userAuth0ID string, filter *model.NotificationDeleteFilter) (int64, error) {
+	query := repo.Database.Where("user_auth0_id = ?", userAuth0ID)
+	if filter != nil {
+		if len(filter.IDs) > 0 {
+			query = query.Where("id IN (?)", filter.IDs)
+		}
+		if len(filter.Types) > 0 {
+			query = query.Where("notification_type IN (?)", filter.Types)
+		}
+		if filter.OlderThan != nil {
+			query = query.Where("created_at < ?", *filter.OlderThan)
+		}
+	}
+
+	result := query.Delete(&model.Notification{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (repo *NotificationRepository) DeleteNotification(id int, userAuth0ID string) error {
+	result := repo.Database.Where("id = ? AND user_auth0_id = ?", id, userAuth0ID).Delete(&model.Notification{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (repo *NotificationRepository) MarkNotificationAsRead(id int, userAuth0ID string) error {
+	count, err := repo.MarkNotificationsAsRead([]int{id}, userAuth0ID)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		var notification model.Notification
+		if result := repo.Database.First(&notification, "id = ? AND user_auth0_id = ?", id, userAuth0ID); result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
+func (repo *NotificationRepository) MarkNotificationsAsRead(ids []int, userAuth0ID string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := repo.Database.Model(&model.Notification{}).
+		Where("id IN (?) AND user_auth0_id = ? AND read = ?", ids, userAuth0ID, false).
+		Updates(map[string]interface{}{"read": true, "read_at": time.Now()})
+
+	return result.RowsAffected, result.Error
+}
+
+func (repo *NotificationRepository) MarkAllNotificationsAsRead(userAuth0ID string) (int64, error) {
+	result := repo.Database.Model(&model.Notification{}).
+		Where("user_auth0_id = ? AND read = ?", userAuth0ID, false).
+		Updates(map[string]interface{}{"read": true, "read_at": time.Now()})
+
+	return result.RowsAffected, result.Error
+}
+
+func (repo *NotificationRepository) CountUnreadNotifications(userAuth0ID string) (int64, error) {
+	var count int64
+	result := repo.Database.Model(&model.Notification{}).
+		Where("user_auth0_id = ? AND read = ?", userAuth0ID, false).
+		Count(&count)
+
+	return count, result.Error
+}
+
+func (repo *NotificationRepository) CountUnreadNotificationsByType(userAuth0ID string) (map[model.NotificationType]int64, error) {
+	var rows []struct {
+		NotificationType model.NotificationType
+		Count            int64
+	}
+
+	result := repo.Database.Model(&model.Notification{}).
+		Select("notification_type, count(*) as count").
+		Where("user_auth0_id = ? AND read = ?", userAuth0ID, false).
+		Group("notification_type").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	counts := make(map[model.NotificationType]int64, len(rows))
+	for _, row := range rows {
+		counts[row.NotificationType] = row.Count
+	}
+
+	return counts, nil
 }
@@ -2,12 +2,20 @@ package repository
 
 import (
 	"notifications-ms/src/model"
+	"notifications-ms/src/utils"
 
 	"github.com/stretchr/testify/mock"
 )
 
 type NotificationRepositoryMock struct {
 	mock.Mock
+	Outbox OutboxRepositoryMock
+}
+
+// Transaction runs fn directly against the mocks; outbox writes are recorded
+// on Outbox.
+func (n *NotificationRepositoryMock) Transaction(fn func(notifications INotificationRepository, outbox IOutboxRepository) error) error {
+	return fn(n, &n.Outbox)
 }
 
 func (n *NotificationRepositoryMock) AddNotification(notification *model.Notification) error {
@@ -18,11 +26,87 @@ func (n *NotificationRepositoryMock) AddNotification(notification *model.Notific
 	return args.Get(0).(error)
 }
 
-func (n *NotificationRepositoryMock) DeleteNotificationsByUserAuth0ID(userAuth0ID string) {
+func (n *NotificationRepositoryMock) AddNotifications(notifications []*model.Notification) error {
+	args := n.Called(notifications)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(error)
+}
 
+func (n *NotificationRepositoryMock) DeleteNotificationsByUserAuth0ID(userAuth0ID string, filter *model.NotificationDeleteFilter) (int64, error) {
+	args := n.Called(userAuth0ID, filter)
+	if args.Get(1) == nil {
+		return args.Get(0).(int64), nil
+	}
+	return args.Get(0).(int64), args.Get(1).(error)
+}
+
+func (n *NotificationRepositoryMock) DeleteNotification(id int, userAuth0ID string) error {
+	args := n.Called(id, userAuth0ID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(error)
 }
 
 func (n *NotificationRepositoryMock) GetNotificationsByUserAuth0ID(userAuth0ID string) []*model.Notification {
 	args := n.Called(userAuth0ID)
 	return args.Get(0).([]*model.Notification)
 }
+
+func (n *NotificationRepositoryMock) GetNotificationsPageByUserAuth0ID(userAuth0ID string, filter *model.NotificationFilter, limit int, after *utils.Cursor) ([]*model.Notification, error) {
+	args := n.Called(userAuth0ID, filter, limit, after)
+	if args.Get(1) == nil {
+		return args.Get(0).([]*model.Notification), nil
+	}
+	return args.Get(0).([]*model.Notification), args.Get(1).(error)
+}
+
+func (n *NotificationRepositoryMock) GetNotificationsAfterID(userAuth0ID string, afterID int, limit int) ([]*model.Notification, error) {
+	args := n.Called(userAuth0ID, afterID, limit)
+	if args.Get(1) == nil {
+		return args.Get(0).([]*model.Notification), nil
+	}
+	return args.Get(0).([]*model.Notification), args.Get(1).(error)
+}
+
+func (n *NotificationRepositoryMock) MarkNotificationAsRead(id int, userAuth0ID string) error {
+	args := n.Called(id, userAuth0ID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(error)
+}
+
+func (n *NotificationRepositoryMock) MarkNotificationsAsRead(ids []int, userAuth0ID string) (int64, error) {
+	args := n.Called(ids, userAuth0ID)
+	if args.Get(1) == nil {
+		return args.Get(0).(int64), nil
+	}
+	return args.Get(0).(int64), args.Get(1).(error)
+}
+
+func (n *NotificationRepositoryMock) MarkAllNotificationsAsRead(userAuth0ID string) (int64, error) {
+	args := n.Called(userAuth0ID)
+	if args.Get(1) == nil {
+		return args.Get(0).(int64), nil
+	}
+	return args.Get(0).(int64), args.Get(1).(error)
+}
+
+func (n *NotificationRepositoryMock) CountUnreadNotifications(userAuth0ID string) (int64, error) {
+	args := n.Called(userAuth0ID)
+	if args.Get(1) == nil {
+		return args.Get(0).(int64), nil
+	}
+	return args.Get(0).(int64), args.Get(1).(error)
+}
+
+func (n *NotificationRepositoryMock) CountUnreadNotificationsByType(userAuth0ID string) (map[model.NotificationType]int64, error) {
+	args := n.Called(userAuth0ID)
+	if args.Get(1) == nil {
+		return args.Get(0).(map[model.NotificationType]int64), nil
+	}
+	return args.Get(0).(map[model.NotificationType]int64), args.Get(1).(error)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"notifications-ms/src/model"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type IOutboxRepository interface {
+	AddEvents([]*model.OutboxEvent) error
+	ClaimEvents(limit int, lease time.Duration) ([]*model.OutboxEvent, error)
+	DeleteEvent(id int) error
+	MarkEventFailed(id int, retryIn time.Duration, lastError string) error
+	CountEvents() (int64, error)
+}
+
+func NewOutboxRepository(database *gorm.DB) IOutboxRepository {
+	return &OutboxRepository{Database: database}
+}
+
+type OutboxRepository struct {
+	Database *gorm.DB
+}
+
+func (repo *OutboxRepository) AddEvents(events []*model.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(events))
+	values := make([]interface{}, 0, len(events)*2)
+	for i, event := range events {
+		placeholders[i] = "(?, ?)"
+		values = append(values, event.Message, event.OccurredAt)
+	}
+
+	query := "INSERT INTO outbox_events (message, occurred_at) VALUES " + strings.Join(placeholders, ", ")
+	return repo.Database.Exec(query, values...).Error
+}
+
+// ClaimEvents picks due events and pushes their next attempt past the lease,
+// so other relays skip them while they are being delivered. An event whose
+// relay dies mid-delivery becomes due again once the lease runs out.
+func (repo *OutboxRepository) ClaimEvents(limit int, lease time.Duration) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+
+	result := repo.Database.Raw(`UPDATE outbox_events SET next_attempt_at = now() + (? * interval '1 millisecond')
+		WHERE id IN (
+			SELECT id FROM outbox_events WHERE next_attempt_at <= now() ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, message, occurred_at, attempts, next_attempt_at, last_error, created_at`,
+		lease.Milliseconds(), limit,
+	).Scan(&events)
+
+	if result.Error != nil && !gorm.IsRecordNotFoundError(result.Error) {
+		return nil, result.Error
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (repo *OutboxRepository) DeleteEvent(id int) error {
+	return repo.Database.Where("id = ?", id).Delete(&model.OutboxEvent{}).Error
+}
+
+func (repo *OutboxRepository) MarkEventFailed(id int, retryIn time.Duration, lastError string) error {
+	return repo.Database.Exec(
+		"UPDATE outbox_events SET attempts = attempts + 1, next_attempt_at = now() + (? * interval '1 millisecond'), last_error = ? WHERE id = ?",
+		retryIn.Milliseconds(), lastError, id,
+	).Error
+}
+
+func (repo *OutboxRepository) CountEvents() (int64, error) {
+	var count int64
+	if result := repo.Database.Model(&model.OutboxEvent{}).Count(&count); result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"notifications-ms/src/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type OutboxRepositoryMock struct {
+	mock.Mock
+}
+
+func (o *OutboxRepositoryMock) AddEvents(events []*model.OutboxEvent) error {
+	args := o.Called(events)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(error)
+}
+
+func (o *OutboxRepositoryMock) ClaimEvents(limit int, lease time.Duration) ([]*model.OutboxEvent, error) {
+	args := o.Called(limit, lease)
+	if args.Get(1) == nil {
+		return args.Get(0).([]*model.OutboxEvent), nil
+	}
+	return nil, args.Get(1).(error)
+}
+
+func (o *OutboxRepositoryMock) DeleteEvent(id int) error {
+	args := o.Called(id)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(error)
+}
+
+func (o *OutboxRepositoryMock) MarkEventFailed(id int, retryIn time.Duration, lastError string) error {
+	args := o.Called(id, retryIn, lastError)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(error)
+}
+
+func (o *OutboxRepositoryMock) CountEvents() (int64, error) {
+	args := o.Called()
+	if args.Get(1) == nil {
+		return args.Get(0).(int64), nil
+	}
+	return args.Get(0).(int64), args.Get(1).(error)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/mapper"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrDeviceNotFound = errors.New("device not found")
+	ErrInvalidDevice  = errors.New("invalid device")
+)
+
+var deviceTokenReassignments = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "device_token_reassignments_total",
+	Help: "Number of device tokens registered by a user other than the one they belonged to.",
+})
+
+type DeviceService struct {
+	DeviceRepo repository.IDeviceRepository
+	Logger     *logrus.Entry
+}
+
+type IDeviceService interface {
+	RegisterDevice(userAuth0ID string, request *dto.RegisterDeviceRequestDTO) (*dto.DeviceDTO, error)
+	UnregisterDevice(id int, userAuth0ID string) error
+	GetDevices(userAuth0ID string) ([]*dto.DeviceDTO, error)
+}
+
+func NewDeviceService(deviceRepository repository.IDeviceRepository, logger *logrus.Entry) IDeviceService {
+	return &DeviceService{
+		DeviceRepo: deviceRepository,
+		Logger:     logger,
+	}
+}
+
+func (service *DeviceService) RegisterDevice(userAuth0ID string, request *dto.RegisterDeviceRequestDTO) (*dto.DeviceDTO, error) {
+	device := &model.Device{
+		UserAuth0ID: userAuth0ID,
+		Token:       strings.TrimSpace(request.Token),
+		Platform:    model.DevicePlatform(strings.ToLower(string(request.Platform))),
+	}
+
+	if err := device.Validate(); err != nil {
+		service.Logger.Debug(err.Error())
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDevice, err.Error())
+	}
+
+	service.Logger.Info(fmt.Sprintf("Registering %s device for user %s", device.Platform, userAuth0ID))
+	previousUserAuth0ID, err := service.DeviceRepo.RegisterDevice(device)
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return nil, err
+	}
+	// Nothing proves the caller owns the token, so a token changing hands
+	// is made visible rather than silently accepted.
+	if previousUserAuth0ID != "" && previousUserAuth0ID != userAuth0ID {
+		deviceTokenReassignments.Inc()
+		service.Logger.Warn(fmt.Sprintf("Device %d moved from user %s to user %s", device.ID, previousUserAuth0ID, userAuth0ID))
+	}
+
+	service.Logger.Info(fmt.Sprintf("Successfully registered device %d for user %s", device.ID, userAuth0ID))
+	return mapper.DeviceToDeviceDTO(device), nil
+}
+
+func (service *DeviceService) UnregisterDevice(id int, userAuth0ID string) error {
+	service.Logger.Info(fmt.Sprintf("Unregistering device %d for user %s", id, userAuth0ID))
+	err := service.DeviceRepo.UnregisterDevice(id, userAuth0ID)
+	if gorm.IsRecordNotFoundError(err) {
+		service.Logger.Debug(err.Error())
+		return ErrDeviceNotFound
+	}
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return err
+	}
+
+	service.Logger.Info(fmt.Sprintf("Successfully unregistered device %d for user %s", id, userAuth0ID))
+	return nil
+}
+
+func (service *DeviceService) GetDevices(userAuth0ID string) ([]*dto.DeviceDTO, error) {
+	devices, err := service.DeviceRepo.GetDevicesByUserAuth0ID(userAuth0ID)
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return nil, err
+	}
+
+	res := make([]*dto.DeviceDTO, len(devices))
+	for i, device := range devices {
+		res[i] = mapper.DeviceToDeviceDTO(device)
+	}
+	return res, nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/migrations"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"notifications-ms/src/utils"
+	"os"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DeviceServiceIntegrationTestSuite struct {
+	suite.Suite
+	service    DeviceService
+	repository repository.IDeviceRepository
+	db         *gorm.DB
+}
+
+func (suite *DeviceServiceIntegrationTestSuite) SetupSuite() {
+	host := os.Getenv("DATABASE_DOMAIN")
+	user := os.Getenv("DATABASE_USERNAME")
+	password := os.Getenv("DATABASE_PASSWORD")
+	name := os.Getenv("DATABASE_SCHEMA")
+	port := os.Getenv("DATABASE_PORT")
+
+	connectionString := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		host,
+		user,
+		password,
+		name,
+		port,
+	)
+	db, _ := gorm.Open("postgres", connectionString)
+
+	migrator, err := migrations.NewMigrator(db.DB(), utils.Logger())
+	suite.Require().NoError(err)
+	suite.Require().NoError(migrator.Up(context.Background()))
+
+	db.Where("1=1").Delete(model.Device{})
+
+	suite.db = db
+	suite.repository = repository.NewDeviceRepository(db)
+	suite.service = DeviceService{
+		DeviceRepo: suite.repository,
+		Logger:     utils.Logger(),
+	}
+}
+
+func TestDeviceServiceIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, new(DeviceServiceIntegrationTestSuite))
+}
+
+func (suite *DeviceServiceIntegrationTestSuite) TestIntegrationDeviceService_RegisterDevice_Pass() {
+	device, err := suite.service.RegisterDevice("auth0Id1|device", &dto.RegisterDeviceRequestDTO{Token: "token-1", Platform: model.FCM})
+
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), 0, device.ID)
+	devices, _ := suite.service.GetDevices("auth0Id1|device")
+	assert.Equal(suite.T(), 1, len(devices))
+	assert.Equal(suite.T(), "token-1", devices[0].Token)
+}
+
+func (suite *DeviceServiceIntegrationTestSuite) TestIntegrationDeviceService_RegisterDevice_MovesTokenToNewUser() {
+	first, _ := suite.service.RegisterDevice("auth0Id2|device", &dto.RegisterDeviceRequestDTO{Token: "token-2", Platform: model.APNs})
+
+	second := &model.Device{UserAuth0ID: "auth0Id3|device", Token: "token-2", Platform: model.APNs}
+	previousUserAuth0ID, err := suite.repository.RegisterDevice(second)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "auth0Id2|device", previousUserAuth0ID)
+	assert.Equal(suite.T(), first.ID, second.ID)
+	previousOwner, _ := suite.service.GetDevices("auth0Id2|device")
+	assert.Empty(suite.T(), previousOwner)
+	newOwner, _ := suite.service.GetDevices("auth0Id3|device")
+	assert.Equal(suite.T(), 1, len(newOwner))
+}
+
+func (suite *DeviceServiceIntegrationTestSuite) TestIntegrationDeviceRepository_RegisterDevice_NewTokenHasNoPreviousUser() {
+	previousUserAuth0ID, err := suite.repository.RegisterDevice(&model.Device{UserAuth0ID: "auth0Id8|device", Token: "token-8", Platform: model.FCM})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "", previousUserAuth0ID)
+}
+
+func (suite *DeviceServiceIntegrationTestSuite) TestIntegrationDeviceService_UnregisterDevice_OtherUsersDevice() {
+	device, _ := suite.service.RegisterDevice("auth0Id4|device", &dto.RegisterDeviceRequestDTO{Token: "token-4", Platform: model.FCM})
+
+	err := suite.service.UnregisterDevice(device.ID, "auth0Id5|device")
+
+	assert.Equal(suite.T(), ErrDeviceNotFound, err)
+	devices, _ := suite.service.GetDevices("auth0Id4|device")
+	assert.Equal(suite.T(), 1, len(devices))
+}
+
+func (suite *DeviceServiceIntegrationTestSuite) TestIntegrationDeviceService_UnregisterDevice_Pass() {
+	device, _ := suite.service.RegisterDevice("auth0Id6|device", &dto.RegisterDeviceRequestDTO{Token: "token-6", Platform: model.FCM})
+
+	err := suite.service.UnregisterDevice(device.ID, "auth0Id6|device")
+
+	assert.Nil(suite.T(), err)
+	devices, _ := suite.service.GetDevices("auth0Id6|device")
+	assert.Empty(suite.T(), devices)
+}
+
+func (suite *DeviceServiceIntegrationTestSuite) TestIntegrationDeviceRepository_DeleteDevicesByToken() {
+	suite.service.RegisterDevice("auth0Id7|device", &dto.RegisterDeviceRequestDTO{Token: "token-7a", Platform: model.FCM})
+	suite.service.RegisterDevice("auth0Id7|device", &dto.RegisterDeviceRequestDTO{Token: "token-7b", Platform: model.FCM})
+
+	count, err := suite.repository.DeleteDevicesByToken([]string{"token-7a", "unknown"})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+	devices, _ := suite.service.GetDevices("auth0Id7|device")
+	assert.Equal(suite.T(), 1, len(devices))
+	assert.Equal(suite.T(), "token-7b", devices[0].Token)
+}
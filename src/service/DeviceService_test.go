@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"notifications-ms/src/utils"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type DeviceServiceUnitTestsSuite struct {
+	suite.Suite
+	deviceRepositoryMock *repository.DeviceRepositoryMock
+	service              DeviceService
+}
+
+func TestDeviceServiceUnitTestsSuite(t *testing.T) {
+	suite.Run(t, new(DeviceServiceUnitTestsSuite))
+}
+
+func (suite *DeviceServiceUnitTestsSuite) SetupTest() {
+	suite.deviceRepositoryMock = new(repository.DeviceRepositoryMock)
+	suite.service = DeviceService{DeviceRepo: suite.deviceRepositoryMock, Logger: utils.Logger()}
+}
+
+func (suite *DeviceServiceUnitTestsSuite) TestDeviceService_RegisterDevice_NormalizesRequest() {
+	suite.deviceRepositoryMock.On("RegisterDevice", &model.Device{UserAuth0ID: "auth0|1", Token: "apns-token", Platform: model.APNs}).Return("", nil).Once()
+
+	device, err := suite.service.RegisterDevice("auth0|1", &dto.RegisterDeviceRequestDTO{Token: "  apns-token\n", Platform: "APNS"})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "apns-token", device.Token)
+	assert.Equal(suite.T(), model.APNs, device.Platform)
+}
+
+func (suite *DeviceServiceUnitTestsSuite) TestDeviceService_RegisterDevice_CountsTokenReassignment() {
+	suite.deviceRepositoryMock.On("RegisterDevice", mock.Anything).Return("auth0|2", nil).Once()
+	before := testutil.ToFloat64(deviceTokenReassignments)
+
+	_, err := suite.service.RegisterDevice("auth0|1", &dto.RegisterDeviceRequestDTO{Token: "fcm-token", Platform: model.FCM})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), before+1, testutil.ToFloat64(deviceTokenReassignments))
+}
+
+func (suite *DeviceServiceUnitTestsSuite) TestDeviceService_RegisterDevice_SameUserIsNotAReassignment() {
+	suite.deviceRepositoryMock.On("RegisterDevice", mock.Anything).Return("auth0|1", nil).Once()
+	before := testutil.ToFloat64(deviceTokenReassignments)
+
+	_, err := suite.service.RegisterDevice("auth0|1", &dto.RegisterDeviceRequestDTO{Token: "fcm-token", Platform: model.FCM})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), before, testutil.ToFloat64(deviceTokenReassignments))
+}
+
+func (suite *DeviceServiceUnitTestsSuite) TestDeviceService_RegisterDevice_UnknownPlatform() {
+	_, err := suite.service.RegisterDevice("auth0|1", &dto.RegisterDeviceRequestDTO{Token: "token", Platform: "pager"})
+
+	assert.True(suite.T(), errors.Is(err, ErrInvalidDevice))
+	suite.deviceRepositoryMock.AssertNotCalled(suite.T(), "RegisterDevice", mock.Anything)
+}
+
+func (suite *DeviceServiceUnitTestsSuite) TestDeviceService_UnregisterDevice_NotFound() {
+	suite.deviceRepositoryMock.On("UnregisterDevice", 5, "auth0|1").Return(gorm.ErrRecordNotFound).Once()
+
+	err := suite.service.UnregisterDevice(5, "auth0|1")
+
+	assert.Equal(suite.T(), ErrDeviceNotFound, err)
+}
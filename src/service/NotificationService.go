@@ -1,51 +1,176 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"notifications-ms/src/dto"
 	"notifications-ms/src/mapper"
+	"notifications-ms/src/model"
 	"notifications-ms/src/repository"
+	"notifications-ms/src/utils"
+	"sort"
 
+	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrInvalidNotification  = errors.New("invalid notification")
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+	ResumePageSize   = 500
+)
+
 type NotificationService struct {
 	NotificationRepo repository.INotificationRepository
+	Publisher        NotificationPublisher
+	Emailer          NotificationEmailer
 	Logger           *logrus.Entry
 }
 
+type NotificationPublisher interface {
+	Publish(*dto.NotificationDTO)
+}
+
+// NotificationPublishers hands every added notification to each of its
+// publishers in turn.
+type NotificationPublishers []NotificationPublisher
+
+func (publishers NotificationPublishers) Publish(notification *dto.NotificationDTO) {
+	for _, publisher := range publishers {
+		publisher.Publish(notification)
+	}
+}
+
+// NotificationEmailer emails an added notification to the address its
+// producer gave. The address is kept apart from the notification so that
+// publishers, which may hand the notification to clients, never see it.
+type NotificationEmailer interface {
+	Email(notification *dto.NotificationDTO, address string)
+}
+
 type INotificationService interface {
-	AddNotification(*dto.NotificationDTO) error
+	AddNotification(*dto.AddNotificationRequestDTO) error
+	AddNotifications([]*dto.AddNotificationRequestDTO) ([]error, error)
 	GetNotifications(userAuth0ID string) []*dto.NotificationDTO
-	DeleteNotifications(userAuth0ID string)
+	GetNotificationsPage(userAuth0ID string, filter *model.NotificationFilter, limit int, cursor string) (*dto.NotificationPageDTO, error)
+	GetNotificationsAfter(userAuth0ID string, afterID int) ([]*dto.NotificationDTO, error)
+	DeleteNotifications(userAuth0ID string, filter *model.NotificationDeleteFilter) (int64, error)
+	DeleteNotification(id int, userAuth0ID string) error
+	MarkAsRead(id int, userAuth0ID string) error
+	MarkManyAsRead(ids []int, userAuth0ID string) (int64, error)
+	MarkAllAsRead(userAuth0ID string) (int64, error)
+	GetUnreadCount(userAuth0ID string, byType bool) (*dto.UnreadCountDTO, error)
 }
 
 func NewNotificationService(notificationRepository repository.INotificationRepository, logger *logrus.Entry) INotificationService {
 	return &NotificationService{
-		notificationRepository,
-		logger,
+		NotificationRepo: notificationRepository,
+		Logger:           logger,
 	}
 }
 
-func (service *NotificationService) AddNotification(notificationDto *dto.NotificationDTO) error {
-	notification := mapper.NotificationDTOToNotification(notificationDto)
+func (service *NotificationService) AddNotification(notificationDto *dto.AddNotificationRequestDTO) error {
+	notification := mapper.NotificationDTOToNotification(&notificationDto.NotificationDTO)
 
 	err := notification.Validate()
 	if err != nil {
 		service.Logger.Debug(err.Error())
-		return err
+		return fmt.Errorf("%w: %s", ErrInvalidNotification, err.Error())
 	}
 	service.Logger.Info(fmt.Sprintf("Adding notification for user %s", notification.UserAuth0ID))
-	errr := service.NotificationRepo.AddNotification(notification)
+	errr := service.NotificationRepo.Transaction(func(notifications repository.INotificationRepository, outbox repository.IOutboxRepository) error {
+		if err := notifications.AddNotification(notification); err != nil {
+			return err
+		}
+		return outbox.AddEvents([]*model.OutboxEvent{notificationAddedEvent(notification)})
+	})
+	if errors.Is(errr, repository.ErrDuplicateNotification) {
+		service.Logger.Info(fmt.Sprintf("Notification %s for user %s was already added", *notification.IdempotencyKey, notification.UserAuth0ID))
+		return nil
+	}
 	if errr != nil {
 		service.Logger.Debug(errr.Error())
 		return errr
 	}
 
 	service.Logger.Info(fmt.Sprintf("Successfully added notification for user %s", notification.UserAuth0ID))
+
+	service.deliver(notification, notificationDto.Email)
+
 	return nil
 }
 
+// AddNotifications validates every notification and stores the valid ones in
+// one batch. The returned slice holds the validation error of each input, nil
+// for valid ones; the error is the storage error, if any.
+func (service *NotificationService) AddNotifications(notificationDtos []*dto.AddNotificationRequestDTO) ([]error, error) {
+	invalid := make([]error, len(notificationDtos))
+	notifications := make([]*model.Notification, 0, len(notificationDtos))
+	emails := make([]string, 0, len(notificationDtos))
+
+	for i, notificationDto := range notificationDtos {
+		notification := mapper.NotificationDTOToNotification(&notificationDto.NotificationDTO)
+		if err := notification.Validate(); err != nil {
+			service.Logger.Debug(err.Error())
+			invalid[i] = fmt.Errorf("%w: %s", ErrInvalidNotification, err.Error())
+			continue
+		}
+		notifications = append(notifications, notification)
+		emails = append(emails, notificationDto.Email)
+	}
+
+	if len(notifications) == 0 {
+		return invalid, nil
+	}
+
+	service.Logger.Info(fmt.Sprintf("Adding batch of %d notifications", len(notifications)))
+	err := service.NotificationRepo.Transaction(func(notificationRepo repository.INotificationRepository, outbox repository.IOutboxRepository) error {
+		if err := notificationRepo.AddNotifications(notifications); err != nil {
+			return err
+		}
+
+		events := make([]*model.OutboxEvent, 0, len(notifications))
+		for _, notification := range notifications {
+			if notification.ID != 0 {
+				events = append(events, notificationAddedEvent(notification))
+			}
+		}
+		return outbox.AddEvents(events)
+	})
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return invalid, err
+	}
+
+	service.Logger.Info(fmt.Sprintf("Successfully added batch of %d notifications", len(notifications)))
+
+	for i, notification := range notifications {
+		if notification.ID != 0 {
+			service.deliver(notification, emails[i])
+		}
+	}
+
+	return invalid, nil
+}
+
+// deliver hands a stored notification to the publishers and, when its
+// producer gave an address, to the emailer.
+func (service *NotificationService) deliver(notification *model.Notification, address string) {
+	published := mapper.NotificationToNotificationDTO(notification)
+
+	if service.Publisher != nil {
+		service.Publisher.Publish(published)
+	}
+	if service.Emailer != nil && address != "" {
+		service.Emailer.Email(published, address)
+	}
+}
+
 func (service *NotificationService) GetNotifications(userAuth0ID string) []*dto.NotificationDTO {
 	service.Logger.Info(fmt.Sprintf("Getting notifications for user %s in database", userAuth0ID))
 	notifications := service.NotificationRepo.GetNotificationsByUserAuth0ID(userAuth0ID)
@@ -59,7 +184,173 @@ func (service *NotificationService) GetNotifications(userAuth0ID string) []*dto.
 	return res
 }
 
-func (service *NotificationService) DeleteNotifications(userAuth0ID string) {
-	service.NotificationRepo.DeleteNotificationsByUserAuth0ID(userAuth0ID)
-	service.Logger.Info(fmt.Sprintf("Successfully deleted notifications for user %s", userAuth0ID))
+func (service *NotificationService) GetNotificationsPage(userAuth0ID string, filter *model.NotificationFilter, limit int, cursor string) (*dto.NotificationPageDTO, error) {
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	var after *utils.Cursor
+	if cursor != "" {
+		decoded, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			service.Logger.Debug(err.Error())
+			return nil, err
+		}
+		after = &decoded
+	}
+
+	service.Logger.Info(fmt.Sprintf("Getting page of notifications for user %s in database", userAuth0ID))
+	notifications, err := service.NotificationRepo.GetNotificationsPageByUserAuth0ID(userAuth0ID, filter, limit+1, after)
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return nil, err
+	}
+
+	page := dto.NotificationPageDTO{}
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[limit-1]
+		page.NextCursor = utils.EncodeCursor(utils.Cursor{ID: last.ID, CreatedAt: last.CreatedAt})
+	}
+
+	page.Notifications = make([]*dto.NotificationDTO, len(notifications))
+	for i := 0; i < len(notifications); i++ {
+		page.Notifications[i] = mapper.NotificationToNotificationDTO(notifications[i])
+	}
+
+	service.Logger.Info(fmt.Sprintf("Successfully got page of notifications for user %s", userAuth0ID))
+	return &page, nil
+}
+
+// GetNotificationsAfter returns at most ResumePageSize notifications, oldest
+// first. Callers page through a longer backlog by passing the last ID returned.
+func (service *NotificationService) GetNotificationsAfter(userAuth0ID string, afterID int) ([]*dto.NotificationDTO, error) {
+	service.Logger.Info(fmt.Sprintf("Getting notifications after %d for user %s in database", afterID, userAuth0ID))
+	notifications, err := service.NotificationRepo.GetNotificationsAfterID(userAuth0ID, afterID, ResumePageSize)
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return nil, err
+	}
+
+	res := make([]*dto.NotificationDTO, len(notifications))
+	for i := 0; i < len(notifications); i++ {
+		res[i] = mapper.NotificationToNotificationDTO(notifications[i])
+	}
+
+	return res, nil
+}
+
+func (service *NotificationService) DeleteNotifications(userAuth0ID string, filter *model.NotificationDeleteFilter) (int64, error) {
+	var count int64
+	err := service.NotificationRepo.Transaction(func(notifications repository.INotificationRepository, outbox repository.IOutboxRepository) error {
+		var err error
+		if count, err = notifications.DeleteNotificationsByUserAuth0ID(userAuth0ID, filter); err != nil || count == 0 {
+			return err
+		}
+		return outbox.AddEvents([]*model.OutboxEvent{model.NewOutboxEvent(fmt.Sprintf("%d notifications deleted for user %s", count, userAuth0ID))})
+	})
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return 0, err
+	}
+
+	service.Logger.Info(fmt.Sprintf("Successfully deleted %d notifications for user %s", count, userAuth0ID))
+	return count, nil
+}
+
+func (service *NotificationService) DeleteNotification(id int, userAuth0ID string) error {
+	service.Logger.Info(fmt.Sprintf("Deleting notification %d for user %s", id, userAuth0ID))
+	err := service.NotificationRepo.Transaction(func(notifications repository.INotificationRepository, outbox repository.IOutboxRepository) error {
+		if err := notifications.DeleteNotification(id, userAuth0ID); err != nil {
+			return err
+		}
+		return outbox.AddEvents([]*model.OutboxEvent{model.NewOutboxEvent(fmt.Sprintf("Notification %d deleted for user %s", id, userAuth0ID))})
+	})
+	if gorm.IsRecordNotFoundError(err) {
+		service.Logger.Debug(err.Error())
+		return ErrNotificationNotFound
+	}
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return err
+	}
+
+	service.Logger.Info(fmt.Sprintf("Successfully deleted notification %d for user %s", id, userAuth0ID))
+	return nil
+}
+
+func (service *NotificationService) MarkAsRead(id int, userAuth0ID string) error {
+	service.Logger.Info(fmt.Sprintf("Marking notification %d as read for user %s", id, userAuth0ID))
+	err := service.NotificationRepo.MarkNotificationAsRead(id, userAuth0ID)
+	if gorm.IsRecordNotFoundError(err) {
+		service.Logger.Debug(err.Error())
+		return ErrNotificationNotFound
+	}
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return err
+	}
+
+	service.Logger.Info(fmt.Sprintf("Successfully marked notification %d as read for user %s", id, userAuth0ID))
+	return nil
+}
+
+func (service *NotificationService) MarkManyAsRead(ids []int, userAuth0ID string) (int64, error) {
+	service.Logger.Info(fmt.Sprintf("Marking %d notifications as read for user %s", len(ids), userAuth0ID))
+	count, err := service.NotificationRepo.MarkNotificationsAsRead(ids, userAuth0ID)
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return 0, err
+	}
+
+	service.Logger.Info(fmt.Sprintf("Successfully marked %d notifications as read for user %s", count, userAuth0ID))
+	return count, nil
+}
+
+func (service *NotificationService) MarkAllAsRead(userAuth0ID string) (int64, error) {
+	service.Logger.Info(fmt.Sprintf("Marking all notifications as read for user %s", userAuth0ID))
+	count, err := service.NotificationRepo.MarkAllNotificationsAsRead(userAuth0ID)
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return 0, err
+	}
+
+	service.Logger.Info(fmt.Sprintf("Successfully marked %d notifications as read for user %s", count, userAuth0ID))
+	return count, nil
+}
+
+func (service *NotificationService) GetUnreadCount(userAuth0ID string, byType bool) (*dto.UnreadCountDTO, error) {
+	service.Logger.Info(fmt.Sprintf("Counting unread notifications for user %s", userAuth0ID))
+
+	if !byType {
+		total, err := service.NotificationRepo.CountUnreadNotifications(userAuth0ID)
+		if err != nil {
+			service.Logger.Debug(err.Error())
+			return nil, err
+		}
+
+		return &dto.UnreadCountDTO{Total: total}, nil
+	}
+
+	counts, err := service.NotificationRepo.CountUnreadNotificationsByType(userAuth0ID)
+	if err != nil {
+		service.Logger.Debug(err.Error())
+		return nil, err
+	}
+
+	res := &dto.UnreadCountDTO{ByType: make([]*dto.UnreadTypeCountDTO, 0, len(counts))}
+	for notificationType, count := range counts {
+		res.Total += count
+		res.ByType = append(res.ByType, &dto.UnreadTypeCountDTO{Type: notificationType, Count: count})
+	}
+	sort.Slice(res.ByType, func(i, j int) bool { return res.ByType[i].Type < res.ByType[j].Type })
+
+	return res, nil
+}
+
+func notificationAddedEvent(notification *model.Notification) *model.OutboxEvent {
+	return model.NewOutboxEvent(fmt.Sprintf("New notification added for user %s, type: %d", notification.UserAuth0ID, *notification.NotificationType))
 }
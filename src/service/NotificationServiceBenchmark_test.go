@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"notifications-ms/src/dto"
+	"notifications-ms/src/migrations"
+	"notifications-ms/src/model"
+	"notifications-ms/src/repository"
+	"notifications-ms/src/utils"
+	"os"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+const benchmarkBatchSize = 50
+
+func benchmarkService(b *testing.B) *NotificationService {
+	connectionString := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		os.Getenv("DATABASE_DOMAIN"),
+		os.Getenv("DATABASE_USERNAME"),
+		os.Getenv("DATABASE_PASSWORD"),
+		os.Getenv("DATABASE_SCHEMA"),
+		os.Getenv("DATABASE_PORT"),
+	)
+	db, err := gorm.Open("postgres", connectionString)
+	if err != nil {
+		b.Skipf("database not available: %v", err)
+	}
+	b.Cleanup(func() {
+		db.Where("user_auth0_id = ?", "auth0Benchmark").Delete(model.Notification{})
+		db.Close()
+	})
+
+	migrator, err := migrations.NewMigrator(db.DB(), utils.Logger())
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+
+	return &NotificationService{
+		NotificationRepo: &repository.NotificationRepository{Database: db},
+		Logger:           utils.Logger(),
+	}
+}
+
+func benchmarkNotification() *dto.AddNotificationRequestDTO {
+	ntype := model.Like
+	return &dto.AddNotificationRequestDTO{NotificationDTO: dto.NotificationDTO{UserAuth0ID: "auth0Benchmark", Message: "Someone liked your post", NotificationType: &ntype}}
+}
+
+func BenchmarkIntegrationNotificationService_AddNotification(b *testing.B) {
+	service := benchmarkService(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := service.AddNotification(benchmarkNotification()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIntegrationNotificationService_AddNotifications(b *testing.B) {
+	service := benchmarkService(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i += benchmarkBatchSize {
+		batch := make([]*dto.AddNotificationRequestDTO, 0, benchmarkBatchSize)
+		for j := i; j < i+benchmarkBatchSize && j < b.N; j++ {
+			batch = append(batch, benchmarkNotification())
+		}
+		itemErrs, err := service.AddNotifications(batch)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, itemErr := range itemErrs {
+			if itemErr != nil {
+				b.Fatal(itemErr)
+			}
+		}
+	}
+}
@@ -1,13 +1,16 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"notifications-ms/src/dto"
+	"notifications-ms/src/migrations"
 	"notifications-ms/src/model"
 	"notifications-ms/src/repository"
 	"notifications-ms/src/utils"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
@@ -39,9 +42,12 @@ func (suite *NotificationServiceIntegrationTestSuite) SetupSuite() {
 	)
 	db, _ := gorm.Open("postgres", connectionString)
 
-	db.AutoMigrate(model.Notification{})
+	migrator, err := migrations.NewMigrator(db.DB(), utils.Logger())
+	suite.Require().NoError(err)
+	suite.Require().NoError(migrator.Up(context.Background()))
 
 	db.Where("1=1").Delete(model.Notification{})
+	db.Where("1=1").Delete(model.OutboxEvent{})
 
 	notificationRepository := repository.NotificationRepository{Database: db}
 
@@ -93,7 +99,7 @@ func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificatio
 		NotificationType: &ntype,
 	}
 
-	err := suite.service.AddNotification(&notificationDTO)
+	err := suite.service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: notificationDTO})
 
 	assert.Equal(suite.T(), nil, err)
 }
@@ -105,17 +111,129 @@ func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificatio
 		Message:          "Test message",
 	}
 
-	err := suite.service.AddNotification(&notificationDTO)
+	err := suite.service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: notificationDTO})
 
 	assert.NotNil(suite.T(), err)
 }
 
+func (suite *NotificationServiceIntegrationTestSuite) countOutboxEvents(message string) int {
+	count := 0
+	suite.db.Model(&model.OutboxEvent{}).Where("message = ?", message).Count(&count)
+	return count
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_AddNotification_RecordsOutboxEvent() {
+	ntype := model.Follow
+	notificationDTO := dto.NotificationDTO{
+		UserAuth0ID:      "auth0Id1|outbox",
+		Message:          "Test message",
+		NotificationType: &ntype,
+	}
+
+	err := suite.service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: notificationDTO})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, suite.countOutboxEvents("New notification added for user auth0Id1|outbox, type: 1"))
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_DeleteNotifications_NothingDeletedRecordsNoOutboxEvent() {
+	count, err := suite.service.DeleteNotifications("auth0Id2|outbox", nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), count)
+	assert.Equal(suite.T(), 0, suite.countOutboxEvents("0 notifications deleted for user auth0Id2|outbox"))
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) createNotification(userAuth0ID string, ntype model.NotificationType, createdAt time.Time) *model.Notification {
+	notification := &model.Notification{
+		Message:          "Test message",
+		UserAuth0ID:      userAuth0ID,
+		NotificationType: &ntype,
+		CreatedAt:        createdAt,
+	}
+	suite.db.Create(notification)
+	return notification
+}
+
 func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_DeleteNotifications_Pass() {
-	userId := "auth0Id2|auth0"
+	userId := "auth0Id4|delete"
+	suite.createNotification(userId, model.Like, time.Now())
+	suite.createNotification(userId, model.Follow, time.Now())
+
+	count, err := suite.service.DeleteNotifications(userId, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), count)
+	assert.Equal(suite.T(), 0, len(suite.service.GetNotifications(userId)))
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_DeleteNotifications_ExactUserOnly() {
+	suite.createNotification("google-oauth2|123", model.Like, time.Now())
+	suite.createNotification("auth0|123", model.Like, time.Now())
+	suite.createNotification("auth0|123'; DROP TABLE notifications; --", model.Like, time.Now())
+
+	count, err := suite.service.DeleteNotifications("google-oauth2|123", nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+	assert.Equal(suite.T(), 1, len(suite.service.GetNotifications("auth0|123")))
 
-	suite.service.DeleteNotifications(userId)
+	count, err = suite.service.DeleteNotifications("auth0|123'; DROP TABLE notifications; --", nil)
 
-	assert.True(suite.T(), true)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+	assert.Equal(suite.T(), 1, len(suite.service.GetNotifications("auth0|123")))
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_DeleteNotification_Pass() {
+	userId := "auth0Id6|delete"
+	notification := suite.createNotification(userId, model.Like, time.Now())
+	suite.createNotification(userId, model.Follow, time.Now())
+
+	err := suite.service.DeleteNotification(notification.ID, userId)
+
+	assert.Nil(suite.T(), err)
+	notifications := suite.service.GetNotifications(userId)
+	assert.Equal(suite.T(), 1, len(notifications))
+	assert.NotEqual(suite.T(), notification.ID, notifications[0].ID)
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_DeleteNotification_OtherUsersNotification() {
+	notification := suite.createNotification("auth0Id7|delete", model.Like, time.Now())
+
+	err := suite.service.DeleteNotification(notification.ID, "auth0Id8|delete")
+
+	assert.Equal(suite.T(), ErrNotificationNotFound, err)
+	assert.Equal(suite.T(), 1, len(suite.service.GetNotifications("auth0Id7|delete")))
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_DeleteNotification_NotFound() {
+	err := suite.service.DeleteNotification(0, "auth0Id7|delete")
+
+	assert.Equal(suite.T(), ErrNotificationNotFound, err)
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_DeleteNotifications_Filtered() {
+	userId := "auth0Id5|delete"
+	old := suite.createNotification(userId, model.Like, time.Now().Add(-48*time.Hour))
+	suite.createNotification(userId, model.Like, time.Now())
+	follow := suite.createNotification(userId, model.Follow, time.Now().Add(-48*time.Hour))
+	comment := suite.createNotification(userId, model.Comment, time.Now())
+
+	olderThan := time.Now().Add(-24 * time.Hour)
+	count, err := suite.service.DeleteNotifications(userId, &model.NotificationDeleteFilter{
+		Types:     []model.NotificationType{model.Like},
+		OlderThan: &olderThan,
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+
+	count, err = suite.service.DeleteNotifications(userId, &model.NotificationDeleteFilter{IDs: []int{follow.ID, comment.ID, old.ID}})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), count)
+	assert.Equal(suite.T(), 1, len(suite.service.GetNotifications(userId)))
 }
 
 func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_GetNotifications_NoNotifications() {
@@ -135,3 +253,123 @@ func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificatio
 	assert.NotNil(suite.T(), notifications)
 	assert.Equal(suite.T(), 2, len(notifications))
 }
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_MarkAsRead_Pass() {
+	notification := suite.notifications[0]
+
+	err := suite.service.MarkAsRead(notification.ID, notification.UserAuth0ID)
+
+	var stored model.Notification
+	suite.db.First(&stored, notification.ID)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), stored.Read)
+	assert.NotNil(suite.T(), stored.ReadAt)
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_MarkAsRead_OtherUsersNotification() {
+	notification := suite.notifications[0]
+
+	err := suite.service.MarkAsRead(notification.ID, "auth0Id2")
+
+	assert.Equal(suite.T(), ErrNotificationNotFound, err)
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_MarkAllAsRead_Pass() {
+	userId := "auth0Id1"
+
+	_, err := suite.service.MarkAllAsRead(userId)
+
+	notifications := suite.service.GetNotifications(userId)
+
+	assert.Nil(suite.T(), err)
+	for _, notification := range notifications {
+		assert.True(suite.T(), notification.Read)
+	}
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_GetUnreadCount_ByType() {
+	userId := "auth0Id2"
+
+	count, err := suite.service.GetUnreadCount(userId, true)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count.Total)
+	assert.Equal(suite.T(), []*dto.UnreadTypeCountDTO{{Type: model.Follow, Count: 1}}, count.ByType)
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_GetNotificationsPage_PagesThroughHistory() {
+	userId := "auth0Id1"
+
+	first, err := suite.service.GetNotificationsPage(userId, nil, 1, "")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(first.Notifications))
+	assert.Equal(suite.T(), suite.notifications[2].ID, first.Notifications[0].ID)
+	assert.NotEqual(suite.T(), "", first.NextCursor)
+
+	second, err := suite.service.GetNotificationsPage(userId, nil, 1, first.NextCursor)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(second.Notifications))
+	assert.Equal(suite.T(), suite.notifications[0].ID, second.Notifications[0].ID)
+	assert.Equal(suite.T(), "", second.NextCursor)
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_GetNotificationsPage_FilteredByType() {
+	userId := "auth0Id1"
+	filter := model.NotificationFilter{Types: []model.NotificationType{model.Follow}}
+
+	page, err := suite.service.GetNotificationsPage(userId, &filter, 10, "")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(page.Notifications))
+	assert.Equal(suite.T(), model.Follow, *page.Notifications[0].NotificationType)
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_GetNotificationsPage_FilteredByTimeRange() {
+	userId := "auth0Id1"
+	since := time.Now().Add(time.Hour)
+	filter := model.NotificationFilter{Since: &since}
+
+	page, err := suite.service.GetNotificationsPage(userId, &filter, 10, "")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, len(page.Notifications))
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_AddNotifications_Pass() {
+	ntype := model.Like
+	notificationDTOs := []*dto.AddNotificationRequestDTO{
+		{NotificationDTO: dto.NotificationDTO{UserAuth0ID: "auth0Batch", Message: "First", NotificationType: &ntype}},
+		{NotificationDTO: dto.NotificationDTO{UserAuth0ID: "auth0Batch", Message: "Second", NotificationType: &ntype}},
+	}
+
+	errs, err := suite.service.AddNotifications(notificationDTOs)
+
+	notifications := suite.service.GetNotifications("auth0Batch")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []error{nil, nil}, errs)
+	assert.Equal(suite.T(), 2, len(notifications))
+	assert.Less(suite.T(), notifications[1].ID, notifications[0].ID)
+}
+
+func (suite *NotificationServiceIntegrationTestSuite) TestIntegrationNotificationService_AddNotification_DuplicateIdempotencyKey() {
+	ntype := model.Like
+	notificationDTO := dto.NotificationDTO{
+		UserAuth0ID:      "auth0Idempotent",
+		Message:          "Someone liked your post",
+		NotificationType: &ntype,
+		IdempotencyKey:   "like-post-1-by-user-2",
+	}
+
+	first := suite.service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: notificationDTO})
+	second := suite.service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: notificationDTO})
+	_, batchErr := suite.service.AddNotifications([]*dto.AddNotificationRequestDTO{{NotificationDTO: notificationDTO}})
+
+	notifications := suite.service.GetNotifications("auth0Idempotent")
+
+	assert.Nil(suite.T(), first)
+	assert.Nil(suite.T(), second)
+	assert.Nil(suite.T(), batchErr)
+	assert.Equal(suite.T(), 1, len(notifications))
+}
@@ -1,13 +1,18 @@
 package service
 
 import (
+	"encoding/json"
 	"notifications-ms/src/dto"
 	"notifications-ms/src/model"
+	"notifications-ms/src/realtime"
 	"notifications-ms/src/repository"
 	"notifications-ms/src/utils"
 	"testing"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -23,6 +28,7 @@ func TestNotificationServiceUnitTestsSuite(t *testing.T) {
 
 func (suite *NotificationServiceUnitTestsSuite) SetupSuite() {
 	suite.notificationRepositoryMock = new(repository.NotificationRepositoryMock)
+	suite.notificationRepositoryMock.Outbox.On("AddEvents", mock.Anything).Return(nil)
 	suite.service = NewNotificationService(suite.notificationRepositoryMock, utils.Logger())
 }
 
@@ -37,7 +43,7 @@ func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotif
 		NotificationType: &ntype,
 	}
 
-	err := suite.service.AddNotification(&notificationDTO)
+	err := suite.service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: notificationDTO})
 
 	assert.NotEqual(suite.T(), nil, err)
 }
@@ -60,7 +66,7 @@ func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotif
 
 	suite.notificationRepositoryMock.On("AddNotification", &notificationEntity).Return(nil)
 
-	err := suite.service.AddNotification(&notificationDTO)
+	err := suite.service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: notificationDTO})
 
 	assert.Equal(suite.T(), nil, err)
 }
@@ -98,3 +104,334 @@ func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_GetNotif
 		assert.Equal(suite.T(), list[i].NotificationType, notifications[i].NotificationType)
 	}
 }
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_MarkAsRead_NotificationExists() {
+	auth0Id := "auth0id"
+	suite.notificationRepositoryMock.On("MarkNotificationAsRead", 1, auth0Id).Return(nil).Once()
+
+	err := suite.service.MarkAsRead(1, auth0Id)
+
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_MarkAsRead_NotificationNotFound() {
+	auth0Id := "auth0id"
+	suite.notificationRepositoryMock.On("MarkNotificationAsRead", 2, auth0Id).Return(gorm.ErrRecordNotFound).Once()
+
+	err := suite.service.MarkAsRead(2, auth0Id)
+
+	assert.Equal(suite.T(), ErrNotificationNotFound, err)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_MarkManyAsRead_ReturnsUpdatedCount() {
+	auth0Id := "auth0id"
+	ids := []int{1, 2, 3}
+	suite.notificationRepositoryMock.On("MarkNotificationsAsRead", ids, auth0Id).Return(int64(3), nil).Once()
+
+	count, err := suite.service.MarkManyAsRead(ids, auth0Id)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), count)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_MarkAllAsRead_ReturnsUpdatedCount() {
+	auth0Id := "auth0id"
+	suite.notificationRepositoryMock.On("MarkAllNotificationsAsRead", auth0Id).Return(int64(5), nil).Once()
+
+	count, err := suite.service.MarkAllAsRead(auth0Id)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(5), count)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_GetUnreadCount_Total() {
+	auth0Id := "auth0id"
+	suite.notificationRepositoryMock.On("CountUnreadNotifications", auth0Id).Return(int64(4), nil).Once()
+
+	count, err := suite.service.GetUnreadCount(auth0Id, false)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(4), count.Total)
+	assert.Nil(suite.T(), count.ByType)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_GetUnreadCount_ByType() {
+	auth0Id := "auth0id"
+	counts := map[model.NotificationType]int64{model.Like: 3, model.Follow: 2}
+	suite.notificationRepositoryMock.On("CountUnreadNotificationsByType", auth0Id).Return(counts, nil).Once()
+
+	count, err := suite.service.GetUnreadCount(auth0Id, true)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(5), count.Total)
+	assert.Equal(suite.T(), []*dto.UnreadTypeCountDTO{{Type: model.Follow, Count: 2}, {Type: model.Like, Count: 3}}, count.ByType)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_GetNotificationsPage_ReturnsNextCursor() {
+	ntype := model.Like
+	auth0Id := "auth0id"
+	now := time.Now()
+	list := []*model.Notification{
+		{ID: 5, Message: "Fifth", UserAuth0ID: auth0Id, NotificationType: &ntype, CreatedAt: now},
+		{ID: 4, Message: "Fourth", UserAuth0ID: auth0Id, NotificationType: &ntype, CreatedAt: now.Add(-time.Minute)},
+		{ID: 3, Message: "Third", UserAuth0ID: auth0Id, NotificationType: &ntype, CreatedAt: now.Add(-2 * time.Minute)},
+	}
+	filter := &model.NotificationFilter{}
+	suite.notificationRepositoryMock.On("GetNotificationsPageByUserAuth0ID", auth0Id, filter, 3, (*utils.Cursor)(nil)).Return(list, nil).Once()
+
+	page, err := suite.service.GetNotificationsPage(auth0Id, filter, 2, "")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(page.Notifications))
+	assert.Equal(suite.T(), list[0].CreatedAt, page.Notifications[0].CreatedAt)
+	assert.Equal(suite.T(), utils.EncodeCursor(utils.Cursor{ID: 4, CreatedAt: list[1].CreatedAt}), page.NextCursor)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_GetNotificationsPage_LastPageHasNoCursor() {
+	ntype := model.Like
+	auth0Id := "auth0id"
+	createdAt := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	list := []*model.Notification{
+		{ID: 2, Message: "Second", UserAuth0ID: auth0Id, NotificationType: &ntype, CreatedAt: createdAt},
+	}
+	after := utils.Cursor{ID: 3, CreatedAt: createdAt.Add(time.Minute)}
+	filter := &model.NotificationFilter{Types: []model.NotificationType{model.Like}}
+	suite.notificationRepositoryMock.On("GetNotificationsPageByUserAuth0ID", auth0Id, filter, 3, &after).Return(list, nil).Once()
+
+	page, err := suite.service.GetNotificationsPage(auth0Id, filter, 2, utils.EncodeCursor(after))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(page.Notifications))
+	assert.Equal(suite.T(), "", page.NextCursor)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_GetNotificationsPage_InvalidCursor() {
+	page, err := suite.service.GetNotificationsPage("auth0id", nil, 2, "not-a-cursor")
+
+	assert.Nil(suite.T(), page)
+	assert.Equal(suite.T(), utils.ErrInvalidCursor, err)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotification_PublishesStoredNotification() {
+	ntype := model.Follow
+	auth0Id := "auth0id-publish"
+	hub := realtime.NewNotificationHub()
+	subscription := hub.Subscribe(auth0Id)
+	service := NotificationService{NotificationRepo: suite.notificationRepositoryMock, Publisher: hub, Logger: utils.Logger()}
+
+	notificationEntity := model.Notification{
+		Message:          "Someone followed you",
+		UserAuth0ID:      auth0Id,
+		NotificationType: &ntype,
+	}
+	suite.notificationRepositoryMock.On("AddNotification", &notificationEntity).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.Notification).ID = 7
+	}).Return(nil).Once()
+
+	err := service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: dto.NotificationDTO{
+		Message:          "Someone followed you",
+		UserAuth0ID:      auth0Id,
+		NotificationType: &ntype,
+	}})
+
+	assert.Nil(suite.T(), err)
+	published := <-subscription.C
+	assert.Equal(suite.T(), 7, published.ID)
+	assert.Equal(suite.T(), auth0Id, published.UserAuth0ID)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_GetNotificationsAfter_ReturnsMissedNotifications() {
+	ntype := model.Like
+	auth0Id := "auth0id"
+	list := []*model.Notification{
+		{ID: 4, Message: "Fourth", UserAuth0ID: auth0Id, NotificationType: &ntype},
+		{ID: 5, Message: "Fifth", UserAuth0ID: auth0Id, NotificationType: &ntype},
+	}
+	suite.notificationRepositoryMock.On("GetNotificationsAfterID", auth0Id, 3, ResumePageSize).Return(list, nil).Once()
+
+	notifications, err := suite.service.GetNotificationsAfter(auth0Id, 3)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(notifications))
+	assert.Equal(suite.T(), 4, notifications[0].ID)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_DeleteNotification_NotificationNotFound() {
+	auth0Id := "auth0id"
+	suite.notificationRepositoryMock.On("DeleteNotification", 3, auth0Id).Return(gorm.ErrRecordNotFound).Once()
+
+	err := suite.service.DeleteNotification(3, auth0Id)
+
+	assert.Equal(suite.T(), ErrNotificationNotFound, err)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_DeleteNotification_NotificationExists() {
+	auth0Id := "auth0id-delete"
+	suite.notificationRepositoryMock.On("DeleteNotification", 4, auth0Id).Return(nil).Once()
+
+	err := suite.service.DeleteNotification(4, auth0Id)
+
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotifications_InvalidOnesAreSkipped() {
+	ntype := model.Like
+	valid := dto.AddNotificationRequestDTO{NotificationDTO: dto.NotificationDTO{Message: "Someone liked your post", UserAuth0ID: "auth0id-batch", NotificationType: &ntype}}
+	invalid := dto.AddNotificationRequestDTO{NotificationDTO: dto.NotificationDTO{Message: "No recipient", NotificationType: &ntype}}
+
+	expected := []*model.Notification{{Message: valid.Message, UserAuth0ID: valid.UserAuth0ID, NotificationType: &ntype}}
+	suite.notificationRepositoryMock.On("AddNotifications", expected).Return(nil).Once()
+
+	errs, err := suite.service.AddNotifications([]*dto.AddNotificationRequestDTO{&invalid, &valid})
+
+	assert.Nil(suite.T(), err)
+	assert.ErrorIs(suite.T(), errs[0], ErrInvalidNotification)
+	assert.Nil(suite.T(), errs[1])
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotification_DuplicateIsNoOp() {
+	ntype := model.Follow
+	auth0Id := "auth0id-duplicate"
+	idempotencyKey := "follow-42"
+	hub := realtime.NewNotificationHub()
+	subscription := hub.Subscribe(auth0Id)
+	service := NotificationService{NotificationRepo: suite.notificationRepositoryMock, Publisher: hub, Logger: utils.Logger()}
+
+	notificationEntity := model.Notification{
+		Message:          "Someone followed you",
+		UserAuth0ID:      auth0Id,
+		NotificationType: &ntype,
+		IdempotencyKey:   &idempotencyKey,
+	}
+	suite.notificationRepositoryMock.On("AddNotification", &notificationEntity).Return(repository.ErrDuplicateNotification).Once()
+
+	err := service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: dto.NotificationDTO{
+		Message:          "Someone followed you",
+		UserAuth0ID:      auth0Id,
+		NotificationType: &ntype,
+		IdempotencyKey:   idempotencyKey,
+	}})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, len(subscription.C))
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_DeleteNotifications_ReturnsDeletedCount() {
+	filter := &model.NotificationDeleteFilter{IDs: []int{1, 2}}
+	suite.notificationRepositoryMock.On("DeleteNotificationsByUserAuth0ID", "auth0|delete", filter).Return(int64(2), nil).Once()
+
+	count, err := suite.service.DeleteNotifications("auth0|delete", filter)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), count)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_DeleteNotifications_RepositoryError() {
+	suite.notificationRepositoryMock.On("DeleteNotificationsByUserAuth0ID", "auth0|delete-error", (*model.NotificationDeleteFilter)(nil)).Return(int64(0), gorm.ErrInvalidTransaction).Once()
+
+	count, err := suite.service.DeleteNotifications("auth0|delete-error", nil)
+
+	assert.Equal(suite.T(), gorm.ErrInvalidTransaction, err)
+	assert.Equal(suite.T(), int64(0), count)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotification_RecordsSystemEvent() {
+	ntype := model.Follow
+	notificationDTO := dto.NotificationDTO{Message: "Someone followed you", UserAuth0ID: "auth0id-event", NotificationType: &ntype}
+	suite.notificationRepositoryMock.On("AddNotification", mock.MatchedBy(func(n *model.Notification) bool { return n.UserAuth0ID == "auth0id-event" })).Return(nil).Once()
+
+	err := suite.service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: notificationDTO})
+
+	assert.Nil(suite.T(), err)
+	suite.notificationRepositoryMock.Outbox.AssertCalled(suite.T(), "AddEvents", mock.MatchedBy(func(events []*model.OutboxEvent) bool {
+		return len(events) == 1 && events[0].Message == "New notification added for user auth0id-event, type: 1"
+	}))
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotification_OutboxFailureFailsWholeChange() {
+	repositoryMock := new(repository.NotificationRepositoryMock)
+	repositoryMock.On("AddNotification", mock.Anything).Return(nil)
+	repositoryMock.Outbox.On("AddEvents", mock.Anything).Return(gorm.ErrInvalidTransaction)
+	publisher := realtime.NewNotificationHub()
+	subscription := publisher.Subscribe("auth0id-outbox")
+	defer publisher.Unsubscribe(subscription)
+	service := NotificationService{NotificationRepo: repositoryMock, Publisher: publisher, Logger: utils.Logger()}
+
+	ntype := model.Like
+	err := service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: dto.NotificationDTO{Message: "Someone liked your post", UserAuth0ID: "auth0id-outbox", NotificationType: &ntype}})
+
+	assert.Equal(suite.T(), gorm.ErrInvalidTransaction, err)
+	assert.Empty(suite.T(), subscription.C)
+}
+
+type recordingPublisher struct {
+	published []*dto.NotificationDTO
+}
+
+func (publisher *recordingPublisher) Publish(notification *dto.NotificationDTO) {
+	publisher.published = append(publisher.published, notification)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotification_PublishesToEveryPublisher() {
+	repositoryMock := new(repository.NotificationRepositoryMock)
+	repositoryMock.On("AddNotification", mock.Anything).Return(nil)
+	repositoryMock.Outbox.On("AddEvents", mock.Anything).Return(nil)
+	first, second := &recordingPublisher{}, &recordingPublisher{}
+	service := NotificationService{NotificationRepo: repositoryMock, Publisher: NotificationPublishers{first, second}, Logger: utils.Logger()}
+
+	ntype := model.Comment
+	err := service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: dto.NotificationDTO{Message: "Someone commented", UserAuth0ID: "auth0id-fanout", NotificationType: &ntype}})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(first.published))
+	assert.Equal(suite.T(), 1, len(second.published))
+	repositoryMock.AssertCalled(suite.T(), "AddNotification", mock.MatchedBy(func(n *model.Notification) bool {
+		return n.UserAuth0ID == "auth0id-fanout"
+	}))
+}
+
+type recordingEmailer struct {
+	notifications []*dto.NotificationDTO
+	addresses     []string
+}
+
+func (emailer *recordingEmailer) Email(notification *dto.NotificationDTO, address string) {
+	emailer.notifications = append(emailer.notifications, notification)
+	emailer.addresses = append(emailer.addresses, address)
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotification_EmailsAddressWithoutPublishingIt() {
+	repositoryMock := new(repository.NotificationRepositoryMock)
+	repositoryMock.On("AddNotification", mock.Anything).Return(nil)
+	repositoryMock.Outbox.On("AddEvents", mock.Anything).Return(nil)
+	hub := realtime.NewNotificationHub()
+	subscription := hub.Subscribe("auth0id-email")
+	emailer := &recordingEmailer{}
+	service := NotificationService{NotificationRepo: repositoryMock, Publisher: hub, Emailer: emailer, Logger: utils.Logger()}
+
+	ntype := model.Comment
+	err := service.AddNotification(&dto.AddNotificationRequestDTO{
+		NotificationDTO: dto.NotificationDTO{Message: "Someone commented", UserAuth0ID: "auth0id-email", NotificationType: &ntype},
+		Email:           "user@example.com",
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"user@example.com"}, emailer.addresses)
+	published := <-subscription.C
+	frame, _ := json.Marshal(published)
+	assert.NotContains(suite.T(), string(frame), "user@example.com")
+}
+
+func (suite *NotificationServiceUnitTestsSuite) TestNotificationService_AddNotification_WithoutAddressIsNotEmailed() {
+	repositoryMock := new(repository.NotificationRepositoryMock)
+	repositoryMock.On("AddNotification", mock.Anything).Return(nil)
+	repositoryMock.Outbox.On("AddEvents", mock.Anything).Return(nil)
+	emailer := &recordingEmailer{}
+	service := NotificationService{NotificationRepo: repositoryMock, Emailer: emailer, Logger: utils.Logger()}
+
+	ntype := model.Like
+	err := service.AddNotification(&dto.AddNotificationRequestDTO{NotificationDTO: dto.NotificationDTO{Message: "Someone liked your post", UserAuth0ID: "auth0id-no-email", NotificationType: &ntype}})
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), emailer.notifications)
+}
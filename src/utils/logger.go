@@ -6,6 +6,14 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+var loggerService = os.Getenv("DATABASE_SCHEMA")
+
+// SetLoggerService sets the "service" field of loggers created afterwards.
+// It is meant to be called once at startup, before any goroutines log.
+func SetLoggerService(service string) {
+	loggerService = service
+}
+
 func Logger() *logrus.Entry {
 	os.Mkdir("./logs", os.ModePerm)
 
@@ -18,7 +26,7 @@ func Logger() *logrus.Entry {
 	logger.Out = file
 
 	contextLogger := logger.WithFields(logrus.Fields{
-		"service": os.Getenv("DATABASE_SCHEMA"),
+		"service": loggerService,
 	})
 
 	return contextLogger